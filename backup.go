@@ -0,0 +1,280 @@
+//Copyright 2018 Peter Büschel
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package awsdefault
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/go-ini/ini"
+)
+
+// files stored inside a backup
+const (
+	BackupCredentials = "credentials"
+	BackupConfig      = "config"
+	BackupSettings    = "awsdefault"
+)
+
+// results of restoring a section
+const (
+	RestoreAdded     = "added"
+	RestoreUnchanged = "unchanged"
+	RestoreConflict  = "conflict"
+	RestoreReplaced  = "replaced"
+)
+
+const (
+	backupMagic   = "AWSDEFAULT-BACKUP-1\n"
+	backupSaltLen = 16
+)
+
+// number of PBKDF2 iterations deriving the key from the passphrase
+var backupIterations = 600000
+
+type (
+	// Backup holds the content of the AWS credentials file, the AWS config file and the
+	// awsdefault settings.
+	Backup struct {
+		Version int               `json:"version"`
+		Created time.Time         `json:"created"`
+		Files   map[string]string `json:"files"`
+	}
+
+	// RestoreResult describes what happened to one section of a file during a restore.
+	RestoreResult struct {
+		File    string `json:"file"`
+		Section string `json:"section"`
+		Action  string `json:"action"`
+	}
+)
+
+// backupFiles returns the paths of the files stored inside a backup
+func backupFiles() map[string]string {
+	return map[string]string{
+		BackupCredentials: credentialsPath(),
+		BackupConfig:      configPath(),
+		BackupSettings:    settingsPath(),
+	}
+}
+
+// backupLoadOptions keeps the names inside the AWS config file as they are; the other files are
+// read case-insensitive like everywhere else in awsdefault
+func backupLoadOptions(name string) ini.LoadOptions {
+	return ini.LoadOptions{Insensitive: name != BackupConfig, Loose: true}
+}
+
+// CreateBackup reads all files belonging to a backup; missing files are skipped.
+func CreateBackup() (*Backup, error) {
+	b := &Backup{Version: 1, Created: time.Now().UTC(), Files: make(map[string]string)}
+	for name, path := range backupFiles() {
+		content, err := ioutil.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		b.Files[name] = string(content)
+	}
+	if len(b.Files) == 0 {
+		return nil, fmt.Errorf("found nothing to back up")
+	}
+	return b, nil
+}
+
+// Encrypt returns the backup encrypted with AES-256-GCM using a key derived from the passphrase.
+func (b *Backup) Encrypt(passphrase string) ([]byte, error) {
+	plain, err := json.Marshal(b)
+	if err != nil {
+		return nil, err
+	}
+	salt := make([]byte, backupSaltLen)
+	if _, err = rand.Read(salt); err != nil {
+		return nil, err
+	}
+	gcm, err := backupCipher(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	out := append([]byte(backupMagic), salt...)
+	out = append(out, nonce...)
+	return gcm.Seal(out, nonce, plain, []byte(backupMagic)), nil
+}
+
+// DecryptBackup opens a backup created by Backup.Encrypt.
+func DecryptBackup(data []byte, passphrase string) (*Backup, error) {
+	if !bytes.HasPrefix(data, []byte(backupMagic)) {
+		return nil, fmt.Errorf("the file is no awsdefault backup")
+	}
+	data = data[len(backupMagic):]
+	if len(data) < backupSaltLen {
+		return nil, fmt.Errorf("the backup is damaged")
+	}
+	gcm, err := backupCipher(passphrase, data[:backupSaltLen])
+	if err != nil {
+		return nil, err
+	}
+	data = data[backupSaltLen:]
+	if len(data) < gcm.NonceSize() {
+		return nil, fmt.Errorf("the backup is damaged")
+	}
+	plain, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], []byte(backupMagic))
+	if err != nil {
+		return nil, fmt.Errorf("wrong passphrase or damaged backup")
+	}
+	b := &Backup{}
+	if err = json.Unmarshal(plain, b); err != nil {
+		return nil, err
+	}
+	if b.Version != 1 {
+		return nil, fmt.Errorf("unsupported version %d of the backup", b.Version)
+	}
+	return b, nil
+}
+
+func backupCipher(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key, err := pbkdf2.Key(sha256.New, passphrase, salt, backupIterations, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// restoreSkipped reports whether a section is empty or holds state instead of data: the default
+// profile of the credentials file and the mode of awsdefault are left as they are
+func restoreSkipped(file string, s *ini.Section) bool {
+	return len(s.Keys()) == 0 ||
+		(file == BackupCredentials && s.Name() == "default") ||
+		(file == BackupSettings && s.Name() == settingsSection)
+}
+
+// Restore merges the backup into the local files. Sections missing locally are added; sections
+// differing from the local ones are conflicts, which are only replaced if overwrite is set. A
+// replaced profile used as default is set as default again. A dry run only reports the results
+// without writing any file.
+func (b *Backup) Restore(dryRun, overwrite bool) ([]RestoreResult, error) {
+	var names []string
+	for n := range b.Files {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	paths := backupFiles()
+
+	var results []RestoreResult
+	for _, name := range names {
+		path, ok := paths[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown file %q inside the backup", name)
+		}
+		saved, err := ini.LoadSources(backupLoadOptions(name), []byte(b.Files[name]))
+		if err != nil {
+			return nil, fmt.Errorf("could not read %s of the backup: %v", name, err)
+		}
+		local, err := ini.LoadSources(backupLoadOptions(name), path)
+		if err != nil {
+			return nil, err
+		}
+		active := ""
+		if name == BackupCredentials {
+			if n, idx, _ := (&CredentialsFile{local, path}).GetUsedProfileNameAndIndex(); idx >= 0 {
+				active = n
+			}
+		}
+		changed, replacedActive := false, false
+		for _, s := range saved.Sections() {
+			if restoreSkipped(name, s) {
+				continue
+			}
+			r := RestoreResult{File: name, Section: s.Name(), Action: RestoreAdded}
+			if l, err := local.GetSection(s.Name()); err == nil {
+				switch {
+				case sectionsEqual(l, s):
+					r.Action = RestoreUnchanged
+				case overwrite:
+					r.Action = RestoreReplaced
+				default:
+					r.Action = RestoreConflict
+				}
+			}
+			results = append(results, r)
+			if r.Action == RestoreAdded || r.Action == RestoreReplaced {
+				copySection(local, s)
+				changed = true
+				replacedActive = replacedActive || r.Action == RestoreReplaced && s.Name() == active
+			}
+		}
+		if changed && !dryRun {
+			if err = saveIni(local, path); err != nil {
+				return nil, err
+			}
+			if replacedActive {
+				if err = reapplyDefault(&CredentialsFile{local, path}, active); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	return results, nil
+}
+
+// reapplyDefault copies the keys of the profile into the default section again and keeps the
+// region of the default section
+func reapplyDefault(f *CredentialsFile, name string) error {
+	if r := f.Content.Section("default").Key("region").String(); len(r) > 0 {
+		name += "@" + r
+	}
+	return f.SetDefaultTo(name)
+}
+
+// sectionsEqual compares the keys and values of two sections
+func sectionsEqual(a, b *ini.Section) bool {
+	if len(a.Keys()) != len(b.Keys()) {
+		return false
+	}
+	for _, k := range a.Keys() {
+		if !b.HasKey(k.Name()) || b.Key(k.Name()).Value() != k.Value() {
+			return false
+		}
+	}
+	return true
+}
+
+// copySection replaces the section of the file with the given one
+func copySection(f *ini.File, s *ini.Section) {
+	f.DeleteSection(s.Name())
+	d := f.Section(s.Name())
+	for _, k := range s.Keys() {
+		d.Key(k.Name()).SetValue(k.Value())
+	}
+}
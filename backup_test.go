@@ -0,0 +1,95 @@
+package awsdefault
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kylelemons/godebug/pretty"
+)
+
+func TestBackup_Encrypt(t *testing.T) {
+	defer func(i int) { backupIterations = i }(backupIterations)
+	backupIterations = 1000
+
+	b := &Backup{Version: 1, Files: map[string]string{BackupCredentials: "[live]\naws_access_key_id=A\n"}}
+	data, err := b.Encrypt("secret")
+	if err != nil {
+		t.Fatalf("Backup.Encrypt() error = %v", err)
+	}
+	got, err := DecryptBackup(data, "secret")
+	if err != nil {
+		t.Fatalf("DecryptBackup() error = %v", err)
+	}
+	if diff := pretty.Compare(b, got); diff != "" {
+		t.Errorf("DecryptBackup() diff: (-want +got)\n%s", diff)
+	}
+	if _, err = DecryptBackup(data, "wrong"); err == nil {
+		t.Errorf("DecryptBackup() no error for a wrong passphrase")
+	}
+	if _, err = DecryptBackup([]byte("[live]"), "secret"); err == nil {
+		t.Errorf("DecryptBackup() no error for a file without backup")
+	}
+}
+
+func TestBackup_Restore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "awsdefault")
+	if err != nil {
+		t.Fatalf("could not create temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	for env, name := range map[string]string{
+		"AWS_SHARED_CREDENTIALS_FILE": "credentials",
+		"AWS_CONFIG_FILE":             "config",
+		"AWSDEFAULT_CONFIG_FILE":      "awsdefault",
+	} {
+		os.Setenv(env, filepath.Join(dir, name))
+		defer os.Unsetenv(env)
+	}
+	local := "[default]\naws_access_key_id=L\n[live]\naws_access_key_id=L\n[dev]\naws_access_key_id=D\n"
+	if err = ioutil.WriteFile(filepath.Join(dir, "credentials"), []byte(local), 0600); err != nil {
+		t.Fatalf("could not write credentials file: %s", err)
+	}
+	b := &Backup{Version: 1, Files: map[string]string{
+		BackupCredentials: "[default]\naws_access_key_id=B\n[live]\naws_access_key_id=B\n[dev]\naws_access_key_id=D\n[new]\naws_access_key_id=N\n",
+		BackupConfig:      "[profile Live]\nregion=eu-west-1\n",
+		BackupSettings:    "[awsdefault]\nmode=credential_process\n[profile live]\ntags=prod\n",
+	}}
+
+	want := []RestoreResult{
+		{File: BackupSettings, Section: "profile live", Action: RestoreAdded},
+		{File: BackupConfig, Section: "profile Live", Action: RestoreAdded},
+		{File: BackupCredentials, Section: "live", Action: RestoreConflict},
+		{File: BackupCredentials, Section: "dev", Action: RestoreUnchanged},
+		{File: BackupCredentials, Section: "new", Action: RestoreAdded},
+	}
+	got, err := b.Restore(true, false)
+	if err != nil {
+		t.Fatalf("Backup.Restore() error = %v", err)
+	}
+	if diff := pretty.Compare(want, got); diff != "" {
+		t.Errorf("Backup.Restore() diff: (-want +got)\n%s", diff)
+	}
+	if _, err = os.Stat(filepath.Join(dir, "config")); !os.IsNotExist(err) {
+		t.Errorf("Backup.Restore() wrote the config file during a dry run")
+	}
+
+	if _, err = b.Restore(false, true); err != nil {
+		t.Fatalf("Backup.Restore() error = %v", err)
+	}
+	file, err := GetCredentialsFile()
+	if err != nil {
+		t.Fatalf("GetCredentialsFile() error = %v", err)
+	}
+	// the replaced live profile was the default profile
+	for n, id := range map[string]string{"default": "B", "live": "B", "new": "N"} {
+		if p, err := file.GetProfileBy(n); err != nil || p.AccessKeyID != id {
+			t.Errorf("Backup.Restore() profile %s = %+v, %v, want %s", n, p, err, id)
+		}
+	}
+	s, _ := GetSettings()
+	if s.Mode() != ModeDefault || !s.HasTag("live", "prod") {
+		t.Errorf("Backup.Restore() settings mode = %v, tags = %v", s.Mode(), s.Tags("live"))
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"runtime"
+
+	"github.com/gotk3/gotk3/gtk"
+	"github.com/peterbueschel/awsdefault"
+)
+
+// responses of the actions of the error dialog
+const (
+	responseOpen gtk.ResponseType = iota + 1
+	responseRetry
+	responseDoctor
+)
+
+type (
+	// failure describes an error for the error dialog
+	failure struct {
+		summary string                      // what failed, e.g. "Unable to switch the profile"
+		err     error                       // the details
+		retry   func() error                // repeats the failed action; nil if it cannot be repeated
+		file    *awsdefault.CredentialsFile // the credentials file to open and to diagnose
+	}
+
+	// errorDialog shows the summary of a failure, its details in an expander and the actions to fix
+	// it: open the credentials file, retry the failed action and run the doctor.
+	errorDialog struct {
+		dialog   *gtk.MessageDialog
+		expander *gtk.Expander
+		details  *gtk.Label
+		failure  failure
+	}
+)
+
+// openCommand returns the command opening the file with the default application of the desktop
+var openCommand = func(path string) *exec.Cmd {
+	switch runtime.GOOS {
+	case "windows":
+		return exec.Command("rundll32", "url.dll,FileProtocolHandler", path)
+	case "darwin":
+		return exec.Command("open", path)
+	}
+	return exec.Command("xdg-open", path)
+}
+
+// newErrorDialog returns the error dialog of the failure above the given window, which can be nil
+func newErrorDialog(parent *gtk.Window, f failure) (*errorDialog, error) {
+	d := &errorDialog{failure: f}
+	var w gtk.IWindow
+	if parent != nil {
+		w = parent
+	}
+	d.dialog = gtk.MessageDialogNew(w, gtk.DIALOG_MODAL, gtk.MESSAGE_ERROR, gtk.BUTTONS_NONE, "%s", f.summary)
+	d.dialog.SetTitle(appName)
+	d.dialog.SetKeepAbove(true)
+	var err error
+	if d.expander, err = gtk.ExpanderNew("Details"); err != nil {
+		return nil, err
+	}
+	if d.details, err = gtk.LabelNew(""); err != nil {
+		return nil, err
+	}
+	d.details.SetLineWrap(true)
+	d.details.SetSelectable(true)
+	d.details.SetXAlign(0)
+	d.expander.Add(d.details)
+	d.setDetails(f.err.Error(), false)
+	box, err := d.dialog.GetContentArea()
+	if err != nil {
+		return nil, err
+	}
+	box.PackStart(d.expander, true, true, 0)
+
+	buttons := []struct {
+		label    string
+		response gtk.ResponseType
+	}{
+		{"Open credentials file", responseOpen},
+		{"Run doctor", responseDoctor},
+		{"Retry", responseRetry},
+		{"Close", gtk.RESPONSE_CLOSE},
+	}
+	for _, b := range buttons {
+		if b.response == responseRetry && f.retry == nil {
+			continue
+		}
+		if b.response != gtk.RESPONSE_CLOSE && f.file == nil {
+			continue
+		}
+		if _, err = d.dialog.AddButton(b.label, b.response); err != nil {
+			return nil, err
+		}
+	}
+	d.dialog.SetDefaultResponse(gtk.RESPONSE_CLOSE)
+	return d, nil
+}
+
+// setDetails replaces the details; expand shows them without a click on the expander
+func (d *errorDialog) setDetails(text string, expand bool) {
+	d.details.SetText(text)
+	if expand {
+		d.expander.SetExpanded(true)
+	}
+}
+
+// respond performs the action of the response and reports whether the dialog stays open. Failed
+// actions show their error as details.
+func (d *errorDialog) respond(r gtk.ResponseType) bool {
+	switch r {
+	case responseOpen:
+		cmd := openCommand(d.failure.file.Path)
+		if err := cmd.Start(); err != nil {
+			d.setDetails(fmt.Sprintf("Unable to open %s: %s", d.failure.file.Path, err), true)
+		} else {
+			go cmd.Wait() // releases the process after the application was closed
+		}
+		return true
+	case responseDoctor:
+		d.setDetails(diagnose(d.failure.file), true)
+		return true
+	case responseRetry:
+		if err := d.failure.retry(); err != nil {
+			d.setDetails(err.Error(), true)
+			return true
+		}
+	}
+	return false
+}
+
+// run shows the dialog until it is closed or the retry succeeded
+func (d *errorDialog) run() {
+	d.dialog.ShowAll()
+	defer d.dialog.Destroy()
+	for d.respond(d.dialog.Run()) {
+	}
+}
+
+// diagnose returns the findings of the doctor for the credentials file as text
+func diagnose(file *awsdefault.CredentialsFile) string {
+	var buf bytes.Buffer
+	for _, f := range awsdefault.Diagnose(file, os.Environ()) {
+		fmt.Fprintf(&buf, "[%s] %s\n", f.Severity, f.Message)
+		if len(f.Hint) > 0 {
+			fmt.Fprintf(&buf, "    fix: %s\n", f.Hint)
+		}
+	}
+	return buf.String()
+}
+
+// showError shows the error dialog of the failure above the given window; errors of the dialog
+// itself are only logged together with the failure
+func showError(parent *gtk.Window, f failure) {
+	d, err := newErrorDialog(parent, f)
+	if err != nil {
+		log.Printf("%s: %s (%s)", f.summary, f.err, err)
+		return
+	}
+	d.run()
+}
+
+// showError shows the error dialog of the failure above the chooser
+func (c *chooser) showError(summary string, err error, retry func() error) {
+	showError(c.window, failure{summary: summary, err: err, retry: retry, file: c.profiles.source})
+}
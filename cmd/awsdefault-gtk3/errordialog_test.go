@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/gotk3/gotk3/gtk"
+	"github.com/peterbueschel/awsdefault"
+)
+
+func Test_errorDialog_respond(t *testing.T) {
+	defer func(f func(string) *exec.Cmd) { openCommand = f }(openCommand)
+	var opened string
+	openCommand = func(path string) *exec.Cmd {
+		opened = path
+		return exec.Command("true")
+	}
+	file, _ := awsdefault.GetCredentialsFile()
+	retries := 0
+	d, err := newErrorDialog(nil, failure{
+		summary: "Unable to switch the profile",
+		err:     fmt.Errorf("permission denied"),
+		retry: func() error {
+			if retries++; retries == 1 {
+				return fmt.Errorf("still denied")
+			}
+			return nil
+		},
+		file: file,
+	})
+	if err != nil {
+		t.Fatalf("newErrorDialog() error = %v", err)
+	}
+	defer d.dialog.Destroy()
+
+	details := func() string {
+		s, _ := d.details.GetText()
+		return s
+	}
+	if details() != "permission denied" || d.expander.GetExpanded() {
+		t.Errorf("newErrorDialog() details = %q, expanded = %v", details(), d.expander.GetExpanded())
+	}
+	if !d.respond(responseOpen) || opened != file.Path {
+		t.Errorf("respond(open) closed the dialog or opened %q, want %q", opened, file.Path)
+	}
+	if !d.respond(responseDoctor) || !strings.Contains(details(), "AWS_PROFILE") || !d.expander.GetExpanded() {
+		t.Errorf("respond(doctor) details = %q", details())
+	}
+	if !d.respond(responseRetry) || details() != "still denied" {
+		t.Errorf("respond(retry) of a failing retry details = %q", details())
+	}
+	if d.respond(responseRetry) {
+		t.Errorf("respond(retry) kept the dialog open after a successful retry")
+	}
+	if d.respond(gtk.RESPONSE_CLOSE) || d.respond(gtk.RESPONSE_DELETE_EVENT) {
+		t.Errorf("respond(close) kept the dialog open")
+	}
+}
+
+func Test_newErrorDialog(t *testing.T) {
+	tests := []struct {
+		name        string
+		f           failure
+		wantButtons []gtk.ResponseType
+	}{
+		{
+			name:        "positive — all actions",
+			f:           failure{err: fmt.Errorf("x"), retry: func() error { return nil }, file: &awsdefault.CredentialsFile{}},
+			wantButtons: []gtk.ResponseType{responseOpen, responseDoctor, responseRetry, gtk.RESPONSE_CLOSE},
+		},
+		{
+			name:        "positive — without retry",
+			f:           failure{err: fmt.Errorf("x"), file: &awsdefault.CredentialsFile{}},
+			wantButtons: []gtk.ResponseType{responseOpen, responseDoctor, gtk.RESPONSE_CLOSE},
+		},
+		{
+			name:        "positive — without file",
+			f:           failure{err: fmt.Errorf("x")},
+			wantButtons: []gtk.ResponseType{gtk.RESPONSE_CLOSE},
+		},
+	}
+	all := []gtk.ResponseType{responseOpen, responseDoctor, responseRetry, gtk.RESPONSE_CLOSE}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := newErrorDialog(nil, tt.f)
+			if err != nil {
+				t.Fatalf("newErrorDialog() error = %v", err)
+			}
+			defer d.dialog.Destroy()
+			var got []gtk.ResponseType
+			for _, r := range all {
+				if _, err := d.dialog.GetWidgetForResponse(r); err == nil {
+					got = append(got, r)
+				}
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.wantButtons) {
+				t.Errorf("newErrorDialog() buttons = %v, want %v", got, tt.wantButtons)
+			}
+		})
+	}
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+
+	"github.com/gotk3/gotk3/gtk"
+	"github.com/peterbueschel/awsdefault"
+)
+
+// groupBy is the criterion given via flag; empty uses group_by of the settings
+var groupBy string
+
+// grouper returns the groups of the given profile; profiles without a group stay at the top level
+type grouper func(name string) []string
+
+// newGrouper returns the grouper of the criterion given via flag or the settings; nil for an
+// ungrouped list
+func (c *chooser) newGrouper(settings *awsdefault.Settings) (grouper, error) {
+	by := groupBy
+	if len(by) == 0 && settings != nil {
+		by = settings.GroupBy()
+	}
+	switch by {
+	case "":
+		return nil, nil
+	case awsdefault.GroupByTag:
+		if settings == nil {
+			return nil, nil
+		}
+		return settings.Tags, nil // a profile appears under each of its tags
+	case awsdefault.GroupByAccount:
+		return func(name string) []string {
+			p, err := c.profiles.file.GetProfileBy(name)
+			if err != nil || len(p.AccountID()) == 0 {
+				return nil
+			}
+			return []string{p.AccountID()}
+		}, nil
+	case awsdefault.GroupByPrefix:
+		re := regexp.MustCompile(awsdefault.DefaultGroupPrefix)
+		if settings != nil {
+			var err error
+			if re, err = settings.GroupPrefix(); err != nil {
+				return nil, err
+			}
+		}
+		return prefixGrouper(re), nil
+	}
+	return nil, fmt.Errorf("unknown group criterion %q, want %s, %s or %s", by,
+		awsdefault.GroupByTag, awsdefault.GroupByAccount, awsdefault.GroupByPrefix)
+}
+
+// prefixGrouper groups the profiles by the first capture group of the rule or, without one, by
+// the whole match
+func prefixGrouper(re *regexp.Regexp) grouper {
+	return func(name string) []string {
+		m := re.FindStringSubmatch(name)
+		switch {
+		case m == nil:
+			return nil
+		case len(m) > 1 && len(m[1]) > 0:
+			return []string{m[1]}
+		case len(m[0]) > 0:
+			return []string{m[0]}
+		}
+		return nil
+	}
+}
+
+// groupProfiles sorts the profiles into their groups; the names of the groups are sorted and the
+// profiles keep their order. noProfile and profiles without a group are returned as ungrouped.
+func groupProfiles(list []string, group grouper) (names []string, members map[string][]string, ungrouped []string) {
+	members = make(map[string][]string)
+	for _, n := range list {
+		var groups []string
+		if group != nil && n != noProfile {
+			groups = group(n)
+		}
+		if len(groups) == 0 {
+			ungrouped = append(ungrouped, n)
+		}
+		for _, g := range groups {
+			if _, ok := members[g]; !ok {
+				names = append(names, g)
+			}
+			members[g] = append(members[g], n)
+		}
+	}
+	sort.Strings(names)
+	return names, members, ungrouped
+}
+
+// setValues sets the given columns of the row
+func setValues(store *gtk.TreeStore, iter *gtk.TreeIter, columns []int, values []interface{}) error {
+	for i, column := range columns {
+		if err := store.SetValue(iter, column, values[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rows returns the rows below the parent in the order of the tree view; all rows of the model, if
+// the parent is nil. Only the children of the rows accepted by descend are included; nil
+// descends into all rows.
+func rows(model *gtk.TreeModel, parent *gtk.TreeIter, descend func(*gtk.TreeIter) bool) []*gtk.TreeIter {
+	var list []*gtk.TreeIter
+	iter := new(gtk.TreeIter)
+	for ok := model.IterChildren(parent, iter); ok; ok = model.IterNext(iter) {
+		row := *iter
+		list = append(list, &row)
+		if model.IterHasChild(&row) && (descend == nil || descend(&row)) {
+			list = append(list, rows(model, &row, descend)...)
+		}
+	}
+	return list
+}
+
+// isGroup tells whether the row is the header of a group instead of a profile
+func isGroup(model *gtk.TreeModel, iter *gtk.TreeIter) bool {
+	value, err := model.GetValue(iter, columnGroup)
+	if err != nil {
+		return false
+	}
+	v, err := value.GoValue()
+	if err != nil {
+		return false
+	}
+	group, _ := v.(bool)
+	return group
+}
+
+// shownRows returns the rows of the tree view, which are neither hidden by the search nor inside
+// a collapsed group, from top to bottom
+func (c *chooser) shownRows() []*gtk.TreeIter {
+	model := &c.filter.TreeModel
+	return rows(model, nil, func(iter *gtk.TreeIter) bool {
+		path, err := model.GetPath(iter)
+		return err == nil && c.view.RowExpanded(path)
+	})
+}
+
+// toggleGroup expands the collapsed group in the given row of the tree view or collapses it
+func (c *chooser) toggleGroup(iter *gtk.TreeIter) {
+	path, err := c.filter.GetPath(iter)
+	if err != nil {
+		return
+	}
+	if c.view.RowExpanded(path) {
+		c.view.CollapseRow(path)
+	} else {
+		c.view.ExpandRow(path, false)
+	}
+}
+
+// expandTo expands the group of the given profile and returns its first row in the tree view; nil,
+// if the search hides the profile
+func (c *chooser) expandTo(name string) (*gtk.TreeIter, error) {
+	model := &c.filter.TreeModel
+	for _, iter := range rows(model, nil, nil) {
+		if isGroup(model, iter) {
+			continue
+		}
+		str, err := nameAt(model, iter)
+		if err != nil {
+			return nil, err
+		}
+		if str != name {
+			continue
+		}
+		path, err := model.GetPath(iter)
+		if err != nil {
+			return nil, err
+		}
+		c.view.ExpandToPath(path)
+		return iter, nil
+	}
+	return nil, nil
+}
+
+// filterRows marks the profiles below the parent matching the query as visible and the groups
+// containing at least one of them; the result tells whether any row below the parent is visible
+func (c *chooser) filterRows(parent *gtk.TreeIter, query string) (bool, error) {
+	model := &c.store.TreeModel
+	found := false
+	iter := new(gtk.TreeIter)
+	for ok := model.IterChildren(parent, iter); ok; ok = model.IterNext(iter) {
+		var visible bool
+		if isGroup(model, iter) {
+			row := *iter
+			var err error
+			if visible, err = c.filterRows(&row, query); err != nil {
+				return false, err
+			}
+		} else {
+			str, err := nameAt(model, iter)
+			if err != nil {
+				return false, err
+			}
+			visible = matches(str, query)
+		}
+		if err := c.store.SetValue(iter, columnVisible, visible); err != nil {
+			return false, err
+		}
+		found = found || visible
+	}
+	return found, nil
+}
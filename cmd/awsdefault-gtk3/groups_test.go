@@ -0,0 +1,105 @@
+package main
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/gotk3/gotk3/gtk"
+	"github.com/kylelemons/godebug/pretty"
+	"github.com/peterbueschel/awsdefault"
+)
+
+func Test_prefixGrouper(t *testing.T) {
+	tests := []struct {
+		name string
+		rule string
+		want []string
+	}{
+		{name: "payments-prod", rule: awsdefault.DefaultGroupPrefix, want: []string{"payments"}},
+		{name: "payments", rule: awsdefault.DefaultGroupPrefix},
+		{name: "payments_prod", rule: `^[a-z]+_`, want: []string{"payments_"}},
+		{name: "-prod", rule: `^([^-]*)-`},
+	}
+	for _, tt := range tests {
+		got := prefixGrouper(regexp.MustCompile(tt.rule))(tt.name)
+		if diff := pretty.Compare(got, tt.want); diff != "" {
+			t.Errorf("prefixGrouper(%q)(%q) diff: (-got +want)\n%s", tt.rule, tt.name, diff)
+		}
+	}
+}
+
+func Test_groupProfiles(t *testing.T) {
+	list := []string{"web-prod", "db-prod", "db-dev", "tools", noProfile}
+	names, members, ungrouped := groupProfiles(list, prefixGrouper(regexp.MustCompile(awsdefault.DefaultGroupPrefix)))
+	if diff := pretty.Compare(names, []string{"db", "web"}); diff != "" {
+		t.Errorf("groupProfiles() names diff: (-got +want)\n%s", diff)
+	}
+	if diff := pretty.Compare(members, map[string][]string{
+		"db": {"db-prod", "db-dev"}, "web": {"web-prod"},
+	}); diff != "" {
+		t.Errorf("groupProfiles() members diff: (-got +want)\n%s", diff)
+	}
+	if diff := pretty.Compare(ungrouped, []string{"tools", noProfile}); diff != "" {
+		t.Errorf("groupProfiles() ungrouped diff: (-got +want)\n%s", diff)
+	}
+	if names, _, ungrouped = groupProfiles(list, nil); len(names) != 0 || len(ungrouped) != len(list) {
+		t.Errorf("groupProfiles() without grouper names = %v, ungrouped = %v", names, ungrouped)
+	}
+}
+
+func Test_chooser_groups(t *testing.T) {
+	defer func(p bool, g string) { permanent, groupBy = p, g }(permanent, groupBy)
+	permanent, groupBy = true, awsdefault.GroupByTag
+	c, cleanup := testChooser(t)
+	defer cleanup()
+	settings, err := awsdefault.GetSettings()
+	if err != nil {
+		t.Fatalf("could not load settings: %s", err)
+	}
+	if err = settings.SetTags("dev", []string{"team-a"}); err != nil {
+		t.Fatalf("could not tag dev: %s", err)
+	}
+	if err = settings.SetTags("live", []string{"team-a", "team-b"}); err != nil {
+		t.Fatalf("could not tag live: %s", err)
+	}
+	if err = c.refresh(c.profiles.source); err != nil {
+		t.Fatalf("refresh() error = %v", err)
+	}
+
+	model := &c.filter.TreeModel
+	var top []string
+	for _, iter := range rows(model, nil, func(*gtk.TreeIter) bool { return false }) {
+		name, _ := nameAt(model, iter)
+		top = append(top, name)
+	}
+	if diff := pretty.Compare(top, []string{"team-a", "team-b", noProfile}); diff != "" {
+		t.Errorf("refresh() top level rows diff: (-got +want)\n%s", diff)
+	}
+	groupA, groupB := rows(model, nil, nil)[0], rows(model, nil, nil)[3]
+	pathA, _ := model.GetPath(groupA)
+	pathB, _ := model.GetPath(groupB)
+	if !c.view.RowExpanded(pathA) || c.view.RowExpanded(pathB) {
+		t.Errorf("refresh() expanded team-a = %v, team-b = %v, want true, false",
+			c.view.RowExpanded(pathA), c.view.RowExpanded(pathB))
+	}
+	if m, iter, ok := c.selection.GetSelected(); !ok {
+		t.Errorf("refresh() selected nothing")
+	} else if str, _ := nameAt(m.(*gtk.TreeModel), iter); str != "dev" {
+		t.Errorf("refresh() selected = %v, want dev", str)
+	}
+
+	c.selection.SelectIter(groupB)
+	if c.profiles.curr != "dev" {
+		t.Errorf("selecting a group switched to %v", c.profiles.curr)
+	}
+	if err = c.activate(); err != nil || !c.view.RowExpanded(pathB) {
+		t.Errorf("activate() on a group error = %v, expanded = %v", err, c.view.RowExpanded(pathB))
+	}
+
+	if err = c.applySearch("dev"); err != nil {
+		t.Fatalf("applySearch() error = %v", err)
+	}
+	if n := c.filter.IterNChildren(nil); n != 1 {
+		t.Errorf("applySearch() visible top level rows = %v, want only team-a", n)
+	}
+}
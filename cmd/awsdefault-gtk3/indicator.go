@@ -0,0 +1,500 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sync"
+
+	"github.com/godbus/dbus"
+	"github.com/godbus/dbus/introspect"
+	"github.com/peterbueschel/awsdefault"
+)
+
+// StatusNotifierItem and the menu of the item via dbusmenu, see
+// https://www.freedesktop.org/wiki/Specifications/StatusNotifierItem/
+const (
+	itemInterface  = "org.kde.StatusNotifierItem"
+	itemPath       = dbus.ObjectPath("/StatusNotifierItem")
+	menuInterface  = "com.canonical.dbusmenu"
+	menuPath       = dbus.ObjectPath("/MenuBar")
+	watcherName    = "org.kde.StatusNotifierWatcher"
+	watcherPath    = dbus.ObjectPath("/StatusNotifierWatcher")
+	propsInterface = "org.freedesktop.DBus.Properties"
+	indicatorIcon  = "dialog-password"
+	menuQuit       = int32(1 << 30) // id of the quit item; the profiles use 1..n
+	menuSeparator  = menuQuit - 1
+	menuRoot       = int32(0)
+	menuVersion    = uint32(3)
+)
+
+const itemIntrospection = `<node>
+	<interface name="org.kde.StatusNotifierItem">
+		<property name="Category" type="s" access="read"/>
+		<property name="Id" type="s" access="read"/>
+		<property name="Title" type="s" access="read"/>
+		<property name="Status" type="s" access="read"/>
+		<property name="IconName" type="s" access="read"/>
+		<property name="ToolTip" type="(sa(iiay)ss)" access="read"/>
+		<property name="ItemIsMenu" type="b" access="read"/>
+		<property name="Menu" type="o" access="read"/>
+		<property name="XAyatanaLabel" type="s" access="read"/>
+		<property name="XAyatanaLabelGuide" type="s" access="read"/>
+		<method name="Activate"><arg name="x" type="i" direction="in"/><arg name="y" type="i" direction="in"/></method>
+		<method name="SecondaryActivate"><arg name="x" type="i" direction="in"/><arg name="y" type="i" direction="in"/></method>
+		<method name="ContextMenu"><arg name="x" type="i" direction="in"/><arg name="y" type="i" direction="in"/></method>
+		<method name="Scroll"><arg name="delta" type="i" direction="in"/><arg name="orientation" type="s" direction="in"/></method>
+		<signal name="NewTitle"/>
+		<signal name="NewToolTip"/>
+		<signal name="NewStatus"><arg name="status" type="s"/></signal>
+		<signal name="XAyatanaNewLabel"><arg name="label" type="s"/><arg name="guide" type="s"/></signal>
+	</interface>` + introspect.IntrospectDataString + propsIntrospection + `</node>`
+
+const menuIntrospection = `<node>
+	<interface name="com.canonical.dbusmenu">
+		<property name="Version" type="u" access="read"/>
+		<property name="TextDirection" type="s" access="read"/>
+		<property name="Status" type="s" access="read"/>
+		<property name="IconThemePath" type="as" access="read"/>
+		<method name="GetLayout">
+			<arg name="parentId" type="i" direction="in"/>
+			<arg name="recursionDepth" type="i" direction="in"/>
+			<arg name="propertyNames" type="as" direction="in"/>
+			<arg name="revision" type="u" direction="out"/>
+			<arg name="layout" type="(ia{sv}av)" direction="out"/>
+		</method>
+		<method name="GetGroupProperties">
+			<arg name="ids" type="ai" direction="in"/>
+			<arg name="propertyNames" type="as" direction="in"/>
+			<arg name="properties" type="a(ia{sv})" direction="out"/>
+		</method>
+		<method name="GetProperty">
+			<arg name="id" type="i" direction="in"/>
+			<arg name="name" type="s" direction="in"/>
+			<arg name="value" type="v" direction="out"/>
+		</method>
+		<method name="Event">
+			<arg name="id" type="i" direction="in"/>
+			<arg name="eventId" type="s" direction="in"/>
+			<arg name="data" type="v" direction="in"/>
+			<arg name="timestamp" type="u" direction="in"/>
+		</method>
+		<method name="EventGroup">
+			<arg name="events" type="a(isvu)" direction="in"/>
+			<arg name="idErrors" type="ai" direction="out"/>
+		</method>
+		<method name="AboutToShow">
+			<arg name="id" type="i" direction="in"/>
+			<arg name="needUpdate" type="b" direction="out"/>
+		</method>
+		<method name="AboutToShowGroup">
+			<arg name="ids" type="ai" direction="in"/>
+			<arg name="updatesNeeded" type="ai" direction="out"/>
+			<arg name="idErrors" type="ai" direction="out"/>
+		</method>
+		<signal name="LayoutUpdated"><arg name="revision" type="u"/><arg name="parent" type="i"/></signal>
+		<signal name="ItemsPropertiesUpdated"><arg name="updatedProps" type="a(ia{sv})"/><arg name="removedProps" type="a(ias)"/></signal>
+	</interface>` + introspect.IntrospectDataString + propsIntrospection + `</node>`
+
+const propsIntrospection = `
+	<interface name="org.freedesktop.DBus.Properties">
+		<method name="Get"><arg name="interface" type="s" direction="in"/><arg name="property" type="s" direction="in"/><arg name="value" type="v" direction="out"/></method>
+		<method name="GetAll"><arg name="interface" type="s" direction="in"/><arg name="props" type="a{sv}" direction="out"/></method>
+		<method name="Set"><arg name="interface" type="s" direction="in"/><arg name="property" type="s" direction="in"/><arg name="value" type="v" direction="in"/></method>
+	</interface>`
+
+type (
+	// indicator shows the current profile in the tray of the desktop, e.g. GNOME (with the
+	// AppIndicator extension) or KDE, and switches the profile via its menu
+	indicator struct {
+		mu       sync.Mutex
+		conn     *dbus.Conn // nil in tests; no signals are emitted then
+		profiles *profiles
+		revision uint32 // of the menu layout
+		done     chan struct{}
+		quit     sync.Once // closes done
+	}
+
+	// indicatorItem implements org.kde.StatusNotifierItem
+	indicatorItem struct{ *indicator }
+
+	// indicatorMenu implements com.canonical.dbusmenu
+	indicatorMenu struct{ *indicator }
+
+	// properties implements org.freedesktop.DBus.Properties for the properties returned by
+	// the function for an interface
+	properties func(iface string) map[string]dbus.Variant
+
+	// toolTip is the (sa(iiay)ss) tooltip of a StatusNotifierItem
+	toolTip struct {
+		IconName    string
+		IconPixmap  []iconPixmap
+		Title       string
+		Description string
+	}
+
+	// iconPixmap is an (iiay) ARGB32 icon of a StatusNotifierItem
+	iconPixmap struct {
+		Width  int32
+		Height int32
+		Data   []byte
+	}
+
+	// menuLayout is an (ia{sv}av) menu item with its children
+	menuLayout struct {
+		ID         int32
+		Properties map[string]dbus.Variant
+		Children   []dbus.Variant
+	}
+
+	// menuItemProperties is an (ia{sv}) menu item without its children
+	menuItemProperties struct {
+		ID         int32
+		Properties map[string]dbus.Variant
+	}
+
+	// menuEvent is an (isvu) event of EventGroup
+	menuEvent struct {
+		ID        int32
+		EventID   string
+		Data      dbus.Variant
+		Timestamp uint32
+	}
+)
+
+func newIndicator(p *profiles) *indicator {
+	return &indicator{profiles: p, revision: 1, done: make(chan struct{})}
+}
+
+// label returns the text shown next to the icon
+func (i *indicator) label() string {
+	if i.profiles.curr == noProfile {
+		return ""
+	}
+	return i.profiles.curr
+}
+
+// itemProperties returns the properties of the StatusNotifierItem
+func (i *indicator) itemProperties(iface string) map[string]dbus.Variant {
+	if iface != itemInterface {
+		return nil
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return map[string]dbus.Variant{
+		"Category":           dbus.MakeVariant("ApplicationStatus"),
+		"Id":                 dbus.MakeVariant(appName),
+		"Title":              dbus.MakeVariant("awsdefault: " + i.profiles.curr),
+		"Status":             dbus.MakeVariant("Active"),
+		"IconName":           dbus.MakeVariant(indicatorIcon),
+		"ToolTip":            dbus.MakeVariant(i.toolTip()),
+		"ItemIsMenu":         dbus.MakeVariant(true),
+		"Menu":               dbus.MakeVariant(menuPath),
+		"XAyatanaLabel":      dbus.MakeVariant(i.label()),
+		"XAyatanaLabelGuide": dbus.MakeVariant(""),
+	}
+}
+
+// toolTip describes the current profile; i.mu must be locked
+func (i *indicator) toolTip() toolTip {
+	t := toolTip{IconName: indicatorIcon, IconPixmap: []iconPixmap{}, Title: "AWS profile: " + i.profiles.curr}
+	if i.profiles.curr != noProfile {
+		if p, err := i.profiles.file.GetProfileBy(i.profiles.curr); err == nil && len(p.Region) > 0 {
+			t.Description = "Region: " + p.Region
+		}
+	}
+	return t
+}
+
+// menuProperties returns the properties of the menu itself
+func (i *indicator) menuProperties(iface string) map[string]dbus.Variant {
+	if iface != menuInterface {
+		return nil
+	}
+	return map[string]dbus.Variant{
+		"Version":       dbus.MakeVariant(menuVersion),
+		"TextDirection": dbus.MakeVariant("ltr"),
+		"Status":        dbus.MakeVariant("normal"),
+		"IconThemePath": dbus.MakeVariant([]string{}),
+	}
+}
+
+// menuItem returns the properties of the menu item with the given id; i.mu must be locked
+func (i *indicator) menuItem(id int32) (map[string]dbus.Variant, bool) {
+	switch {
+	case id == menuRoot:
+		return map[string]dbus.Variant{"children-display": dbus.MakeVariant("submenu")}, true
+	case id == menuSeparator:
+		return map[string]dbus.Variant{"type": dbus.MakeVariant("separator")}, true
+	case id == menuQuit:
+		return map[string]dbus.Variant{"label": dbus.MakeVariant("Quit")}, true
+	case id < 1 || int(id) > len(i.profiles.list):
+		return nil, false
+	}
+	name := i.profiles.list[id-1]
+	state := int32(0)
+	if name == i.profiles.curr {
+		state = 1
+	}
+	return map[string]dbus.Variant{
+		"label":        dbus.MakeVariant(name),
+		"toggle-type":  dbus.MakeVariant("radio"),
+		"toggle-state": dbus.MakeVariant(state),
+	}, true
+}
+
+// menuIDs returns the ids of all items below the root
+func (i *indicator) menuIDs() []int32 {
+	ids := make([]int32, 0, len(i.profiles.list)+2)
+	for n := range i.profiles.list {
+		ids = append(ids, int32(n+1))
+	}
+	return append(ids, menuSeparator, menuQuit)
+}
+
+// layout returns the menu item with the given id and its children
+func (i *indicator) layout(id int32) (menuLayout, bool) {
+	props, ok := i.menuItem(id)
+	if !ok {
+		return menuLayout{}, false
+	}
+	l := menuLayout{ID: id, Properties: props, Children: []dbus.Variant{}}
+	if id == menuRoot {
+		for _, child := range i.menuIDs() {
+			c, _ := i.layout(child)
+			l.Children = append(l.Children, dbus.MakeVariant(c))
+		}
+	}
+	return l, true
+}
+
+// clicked switches to the profile of the menu item or quits the indicator
+func (i *indicator) clicked(id int32) error {
+	if id == menuQuit {
+		i.quit.Do(func() { close(i.done) })
+		return nil
+	}
+	i.mu.Lock()
+	if id < 1 || int(id) > len(i.profiles.list) {
+		i.mu.Unlock()
+		return fmt.Errorf("unknown menu item %d", id)
+	}
+	name := i.profiles.list[id-1]
+	var err error
+	if name == noProfile {
+		err = i.profiles.file.UnSetDefault()
+	} else {
+		err = i.profiles.file.SetDefaultTo(name)
+	}
+	if err == nil {
+		i.profiles.curr, i.profiles.currIdx = name, int(id-1)
+	}
+	i.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return i.changed()
+}
+
+// reload shows the profiles of the reloaded credentials file
+func (i *indicator) reload(file *awsdefault.CredentialsFile) error {
+	i.mu.Lock()
+	i.profiles.reload(file)
+	i.mu.Unlock()
+	return i.changed()
+}
+
+// changed tells the tray, that the profile or the list of profiles changed
+func (i *indicator) changed() error {
+	i.mu.Lock()
+	i.revision++
+	revision, label := i.revision, i.label()
+	i.mu.Unlock()
+	if i.conn == nil {
+		return nil
+	}
+	for _, s := range []struct {
+		path   dbus.ObjectPath
+		name   string
+		values []interface{}
+	}{
+		{itemPath, itemInterface + ".NewTitle", nil},
+		{itemPath, itemInterface + ".NewToolTip", nil},
+		{itemPath, itemInterface + ".XAyatanaNewLabel", []interface{}{label, ""}},
+		{menuPath, menuInterface + ".LayoutUpdated", []interface{}{revision, menuRoot}},
+	} {
+		if err := i.conn.Emit(s.path, s.name, s.values...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Activate is called by a left click; the host shows the menu instead, because ItemIsMenu is
+// set.
+func (item indicatorItem) Activate(x, y int32) *dbus.Error { return nil }
+
+// SecondaryActivate is called by a middle click.
+func (item indicatorItem) SecondaryActivate(x, y int32) *dbus.Error { return nil }
+
+// ContextMenu is called by a right click, if the host does not show the menu itself.
+func (item indicatorItem) ContextMenu(x, y int32) *dbus.Error { return nil }
+
+// Scroll is called by the mouse wheel over the icon.
+func (item indicatorItem) Scroll(delta int32, orientation string) *dbus.Error { return nil }
+
+// GetLayout returns the menu below the given item; the depth and the property names are
+// ignored, the menu is small anyway.
+func (menu indicatorMenu) GetLayout(parentID, recursionDepth int32, propertyNames []string) (uint32, menuLayout, *dbus.Error) {
+	menu.mu.Lock()
+	defer menu.mu.Unlock()
+	l, ok := menu.layout(parentID)
+	if !ok {
+		return 0, l, dbus.MakeFailedError(fmt.Errorf("unknown menu item %d", parentID))
+	}
+	return menu.revision, l, nil
+}
+
+// GetGroupProperties returns the properties of the given menu items; unknown items are skipped.
+func (menu indicatorMenu) GetGroupProperties(ids []int32, propertyNames []string) ([]menuItemProperties, *dbus.Error) {
+	menu.mu.Lock()
+	defer menu.mu.Unlock()
+	items := []menuItemProperties{}
+	for _, id := range ids {
+		if props, ok := menu.menuItem(id); ok {
+			items = append(items, menuItemProperties{ID: id, Properties: props})
+		}
+	}
+	return items, nil
+}
+
+// GetProperty returns a single property of a menu item.
+func (menu indicatorMenu) GetProperty(id int32, name string) (dbus.Variant, *dbus.Error) {
+	menu.mu.Lock()
+	defer menu.mu.Unlock()
+	props, _ := menu.menuItem(id)
+	if v, ok := props[name]; ok {
+		return v, nil
+	}
+	return dbus.Variant{}, dbus.MakeFailedError(fmt.Errorf("menu item %d has no property %s", id, name))
+}
+
+// Event handles the clicks on the menu items.
+func (menu indicatorMenu) Event(id int32, eventID string, data dbus.Variant, timestamp uint32) *dbus.Error {
+	if eventID != "clicked" {
+		return nil
+	}
+	if err := menu.clicked(id); err != nil {
+		log.Println(err)
+		return dbus.MakeFailedError(err)
+	}
+	return nil
+}
+
+// EventGroup handles several events at once and returns the ids of the failed ones.
+func (menu indicatorMenu) EventGroup(events []menuEvent) ([]int32, *dbus.Error) {
+	failed := []int32{}
+	for _, e := range events {
+		if err := menu.Event(e.ID, e.EventID, e.Data, e.Timestamp); err != nil {
+			failed = append(failed, e.ID)
+		}
+	}
+	return failed, nil
+}
+
+// AboutToShow is called before the menu is shown; the layout is always up to date.
+func (menu indicatorMenu) AboutToShow(id int32) (bool, *dbus.Error) { return false, nil }
+
+// AboutToShowGroup is AboutToShow for several menus.
+func (menu indicatorMenu) AboutToShowGroup(ids []int32) ([]int32, []int32, *dbus.Error) {
+	return []int32{}, []int32{}, nil
+}
+
+// Get returns a single property.
+func (p properties) Get(iface, name string) (dbus.Variant, *dbus.Error) {
+	if v, ok := p(iface)[name]; ok {
+		return v, nil
+	}
+	return dbus.Variant{}, dbus.NewError(propsInterface+".Error.PropertyNotFound", []interface{}{name})
+}
+
+// GetAll returns all properties of the interface.
+func (p properties) GetAll(iface string) (map[string]dbus.Variant, *dbus.Error) {
+	props := p(iface)
+	if props == nil {
+		return nil, dbus.NewError(propsInterface+".Error.InterfaceNotFound", []interface{}{iface})
+	}
+	return props, nil
+}
+
+// Set fails, all properties are read-only.
+func (p properties) Set(iface, name string, v dbus.Variant) *dbus.Error {
+	return dbus.NewError(propsInterface+".Error.ReadOnly", []interface{}{name})
+}
+
+// export publishes the item and its menu on the session bus and registers the item at the
+// StatusNotifierWatcher of the desktop
+func (i *indicator) export(conn *dbus.Conn) error {
+	i.conn = conn
+	for _, e := range []struct {
+		v     interface{}
+		path  dbus.ObjectPath
+		iface string
+	}{
+		{indicatorItem{i}, itemPath, itemInterface},
+		{properties(i.itemProperties), itemPath, propsInterface},
+		{introspect.Introspectable(itemIntrospection), itemPath, "org.freedesktop.DBus.Introspectable"},
+		{indicatorMenu{i}, menuPath, menuInterface},
+		{properties(i.menuProperties), menuPath, propsInterface},
+		{introspect.Introspectable(menuIntrospection), menuPath, "org.freedesktop.DBus.Introspectable"},
+	} {
+		if err := conn.Export(e.v, e.path, e.iface); err != nil {
+			return err
+		}
+	}
+	name := fmt.Sprintf("%s-%d-1", itemInterface, os.Getpid())
+	reply, err := conn.RequestName(name, dbus.NameFlagDoNotQueue)
+	if err != nil {
+		return err
+	}
+	if reply != dbus.RequestNameReplyPrimaryOwner {
+		return fmt.Errorf("the name %s is already taken on the session bus", name)
+	}
+	err = conn.Object(watcherName, watcherPath).Call(watcherName+".RegisterStatusNotifierItem", 0, name).Err
+	if err != nil {
+		return fmt.Errorf("could not register at the StatusNotifierWatcher, is a tray running? %s", err)
+	}
+	return nil
+}
+
+// runIndicator shows the indicator until Quit is clicked; changes of the credentials file by
+// other tools show up immediately.
+func runIndicator(p *profiles) error {
+	conn, err := dbus.SessionBus()
+	if err != nil {
+		return err
+	}
+	i := newIndicator(p)
+	if err = i.export(conn); err != nil {
+		return err
+	}
+	events, err := p.source.Watch(i.done)
+	if err != nil {
+		return err
+	}
+	for {
+		select {
+		case e, ok := <-events:
+			if !ok {
+				return nil
+			}
+			if e.Err != nil {
+				log.Println(e.Err)
+			} else if err := i.reload(e.File); err != nil {
+				log.Println(err)
+			}
+		case <-i.done:
+			return nil
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/godbus/dbus"
+)
+
+func Test_indicator_menu(t *testing.T) {
+	c, cleanup := testChooser(t)
+	defer cleanup()
+	i := newIndicator(c.profiles)
+	menu := indicatorMenu{i}
+
+	_, l, err := menu.GetLayout(menuRoot, -1, nil)
+	if err != nil {
+		t.Fatalf("GetLayout() error = %v", err)
+	}
+	// dev, live, noProfile, separator and quit
+	if len(l.Children) != 5 {
+		t.Fatalf("GetLayout() children got = %v, want 5", len(l.Children))
+	}
+	if v, _ := menu.GetProperty(1, "toggle-state"); v.Value() != int32(1) {
+		t.Errorf("GetProperty() toggle-state of dev got = %v, want 1", v.Value())
+	}
+	if _, _, err = menu.GetLayout(42, -1, nil); err == nil {
+		t.Errorf("GetLayout() no error for an unknown item")
+	}
+
+	if err := menu.Event(2, "clicked", dbus.MakeVariant(0), 0); err != nil {
+		t.Fatalf("Event() error = %v", err)
+	}
+	if i.profiles.curr != "live" || i.label() != "live" {
+		t.Errorf("Event() curr got = %v, want live", i.profiles.curr)
+	}
+	if n, _, _ := i.profiles.file.GetUsedProfileNameAndIndex(); n != "live" {
+		t.Errorf("Event() default profile got = %v, want live", n)
+	}
+	if i.revision != 2 {
+		t.Errorf("Event() revision got = %v, want 2", i.revision)
+	}
+	if failed, _ := menu.EventGroup([]menuEvent{{ID: 42, EventID: "clicked"}}); len(failed) != 1 {
+		t.Errorf("EventGroup() failed got = %v, want [42]", failed)
+	}
+
+	if err := menu.Event(menuQuit, "clicked", dbus.MakeVariant(0), 0); err != nil {
+		t.Fatalf("Event() quit error = %v", err)
+	}
+	select {
+	case <-i.done:
+	default:
+		t.Errorf("Event() quit did not stop the indicator")
+	}
+	// a second click, e.g. of a tray sending the event twice, must not panic
+	if err := menu.Event(menuQuit, "clicked", dbus.MakeVariant(0), 0); err != nil {
+		t.Errorf("Event() second quit error = %v", err)
+	}
+}
+
+func Test_indicator_properties(t *testing.T) {
+	c, cleanup := testChooser(t)
+	defer cleanup()
+	i := newIndicator(c.profiles)
+	props := properties(i.itemProperties)
+
+	if v, err := props.Get(itemInterface, "XAyatanaLabel"); err != nil || v.Value() != "dev" {
+		t.Errorf("Get() label got = %v, %v, want dev", v, err)
+	}
+	if _, err := props.Get(itemInterface, "xxxxxxx"); err == nil {
+		t.Errorf("Get() no error for an unknown property")
+	}
+	if _, err := props.GetAll("xxxxxxx"); err == nil {
+		t.Errorf("GetAll() no error for an unknown interface")
+	}
+	all, err := props.GetAll(itemInterface)
+	if err != nil {
+		t.Fatalf("GetAll() error = %v", err)
+	}
+	if tip, ok := all["ToolTip"].Value().(toolTip); !ok || tip.Title != "AWS profile: dev" {
+		t.Errorf("GetAll() tooltip got = %v", all["ToolTip"])
+	}
+	if err := props.Set(itemInterface, "Title", dbus.MakeVariant("x")); err == nil {
+		t.Errorf("Set() no error for a read-only property")
+	}
+}
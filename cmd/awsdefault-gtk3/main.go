@@ -5,7 +5,10 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strings"
+	"time"
 
+	"github.com/gotk3/gotk3/gdk"
 	"github.com/gotk3/gotk3/glib"
 	"github.com/gotk3/gotk3/gtk"
 	"github.com/peterbueschel/awsdefault"
@@ -15,10 +18,45 @@ const (
 	noProfile   = "--No Profile--"
 	appName     = "awsdefault-ui"
 	columnTitle = "Select AWS Profile"
+	// maxListHeight limits the height of the list in pixels; longer lists are scrollable
+	maxListHeight = 480
+	// countdownInterval is the time in milliseconds between two updates of the expiry countdowns
+	countdownInterval = 30000
+	// productionBackground highlights the rows of profiles tagged as production profiles
+	productionBackground = "#f6d5d5"
 )
 
+// columns of the tree store
+const (
+	columnName       = iota
+	columnVisible    // false, if the profile is hidden by the search
+	columnColor      // environment colour of the profile
+	columnIcon       // icon name of the profile type
+	columnRegion     // region of the profile
+	columnKeyID      // masked AWS_ACCESS_KEY_ID
+	columnExpiry     // countdown until the temporary credentials expire
+	columnExpiresAt  // expiry as unix time, 0 for credentials without expiry
+	columnBackground // row background of production profiles
+	columnGroup      // true for the header row of a group
+)
+
+// columnTypes are the types of the columns of the tree store
+var columnTypes = []glib.Type{
+	glib.TYPE_STRING, glib.TYPE_BOOLEAN, glib.TYPE_STRING, glib.TYPE_STRING, glib.TYPE_STRING,
+	glib.TYPE_STRING, glib.TYPE_STRING, glib.TYPE_INT64, glib.TYPE_STRING, glib.TYPE_BOOLEAN,
+}
+
+// typeIcons maps the profile types onto the names of their icons
+var typeIcons = map[string]string{
+	awsdefault.TypeStatic:  "dialog-password",
+	awsdefault.TypeSession: "appointment-soon",
+	awsdefault.TypeRole:    "system-users",
+	awsdefault.TypeSSO:     "web-browser",
+}
+
 var (
 	permanent bool
+	indicate  bool
 )
 
 type (
@@ -26,12 +64,18 @@ type (
 		curr    string
 		currIdx int
 		list    []string
-		file    *awsdefault.CredentialsFile
+		file    awsdefault.Switcher
+		source  *awsdefault.CredentialsFile // the file behind the switcher
 	}
 	chooser struct {
 		selection *gtk.TreeSelection
+		changed   glib.SignalHandle
 		view      *gtk.TreeView
-		store     *gtk.ListStore
+		store     *gtk.TreeStore
+		filter    *gtk.TreeModelFilter
+		search    *gtk.SearchEntry
+		scroll    *gtk.ScrolledWindow
+		menu      *gtk.Menu // the shown context menu
 		window    *gtk.Window
 		box       *gtk.Box
 		err       error
@@ -41,79 +85,264 @@ type (
 
 func (c *chooser) selectionChanged() error {
 	model, iter, ok := c.selection.GetSelected()
-	if ok {
-		tpath, err := model.(*gtk.TreeModel).GetPath(iter)
-		if err != nil {
-			return err
-		}
-		iter, err := c.store.GetIter(tpath)
-		if err != nil {
+	if !ok || isGroup(model.(*gtk.TreeModel), iter) {
+		return nil
+	}
+	str, err := nameAt(model.(*gtk.TreeModel), iter)
+	if err != nil {
+		return err
+	}
+	if err = c.switchTo(str); err == errCancelled {
+		c.selection.HandlerBlock(c.changed)
+		defer c.selection.HandlerUnblock(c.changed)
+		return c.selectCurrent()
+	}
+	return err
+}
+
+// switchTo sets the given profile as default profile. Profiles with MFA are replaced by their
+// session profile, which is created first, if needed; errCancelled tells that the user cancelled
+// the MFA dialog.
+func (c *chooser) switchTo(str string) error {
+	if str == noProfile {
+		if err := c.profiles.file.UnSetDefault(); err != nil {
 			return err
 		}
-		value, err := c.store.GetValue(iter, 0)
-		if err != nil {
-			return err
+		c.profiles.curr = str
+		return nil
+	}
+	target, err := c.resolveMFA(str)
+	if err != nil {
+		return err
+	}
+	if err = c.profiles.file.SetDefaultTo(target); err != nil {
+		return err
+	}
+	if target != str { // shows the (new) session profile as current profile
+		return c.refresh(c.profiles.source)
+	}
+	c.profiles.curr = str
+	return nil
+}
+
+// nameAt returns the name of the profile or of the group in the given row
+func nameAt(model *gtk.TreeModel, iter *gtk.TreeIter) (string, error) {
+	value, err := model.GetValue(iter, columnName)
+	if err != nil {
+		return "", err
+	}
+	return value.GetString()
+}
+
+// matches tells whether the name contains the query or at least all characters of the query in
+// the same order, e.g. "prdb" matches "prod-db"; the case is ignored
+func matches(name, query string) bool {
+	name, query = strings.ToLower(name), strings.ToLower(query)
+	if strings.Contains(name, query) {
+		return true
+	}
+	q := []rune(query)
+	for _, r := range name {
+		if len(q) > 0 && r == q[0] {
+			q = q[1:]
 		}
-		str, err := value.GetString()
+	}
+	return len(q) == 0
+}
+
+// applySearch hides all profiles not matching the query and the groups without matches. During a
+// search all groups are expanded; afterwards only the group of the current profile. The selection
+// handler is blocked, because hiding the selected row changes the selection.
+func (c *chooser) applySearch(query string) error {
+	if c.selection != nil {
+		c.selection.HandlerBlock(c.changed)
+		defer c.selection.HandlerUnblock(c.changed)
+	}
+	if _, err := c.filterRows(nil, query); err != nil {
+		return err
+	}
+	if c.view == nil {
+		return nil
+	}
+	if len(query) > 0 {
+		c.view.ExpandAll()
+		return nil
+	}
+	c.view.CollapseAll()
+	_, err := c.expandTo(c.profiles.curr)
+	return err
+}
+
+// query returns the text of the search entry
+func (c *chooser) query() string {
+	if c.search == nil {
+		return ""
+	}
+	q, _ := c.search.GetText()
+	return q
+}
+
+// highlight moves the selection to the next or the previous shown row without switching to it;
+// Enter switches to the highlighted profile or toggles the highlighted group.
+func (c *chooser) highlight(next bool) {
+	model := &c.filter.TreeModel
+	shown := c.shownRows()
+	if len(shown) == 0 {
+		return
+	}
+	target := shown[0]
+	if _, iter, ok := c.selection.GetSelected(); ok {
+		selected, err := model.GetPath(iter)
 		if err != nil {
-			return err
-		}
-		if str == noProfile {
-			err = c.profiles.file.UnSetDefault()
-		} else {
-			err = c.profiles.file.SetDefaultTo(str)
+			return
 		}
-		if err != nil {
-			return err
+		for i, row := range shown {
+			if path, err := model.GetPath(row); err != nil || path.String() != selected.String() {
+				continue
+			}
+			if next && i+1 >= len(shown) || !next && i == 0 {
+				return // already at the end of the list
+			}
+			if next {
+				target = shown[i+1]
+			} else {
+				target = shown[i-1]
+			}
+			break
 		}
-		c.profiles.curr = str
 	}
-	return nil
+	path, err := model.GetPath(target)
+	if err != nil {
+		return
+	}
+	c.selection.HandlerBlock(c.changed)
+	defer c.selection.HandlerUnblock(c.changed)
+	c.view.SetCursor(path, c.view.GetColumn(0), false)
 }
 
-func showError(msg string) error {
-	c := new(chooser)
-	c.setupWindow()
-	if c.err != nil {
-		return fmt.Errorf("Unable to create window: %s", c.err)
+// activate switches to the highlighted profile or, if none is highlighted, to the first visible
+// profile and closes the popup. A highlighted group gets expanded or collapsed instead.
+func (c *chooser) activate() error {
+	model := &c.filter.TreeModel
+	_, iter, ok := c.selection.GetSelected()
+	if ok && isGroup(model, iter) {
+		c.toggleGroup(iter)
+		return nil
 	}
-	btn, err := gtk.ButtonNewWithLabel(msg)
-	if err != nil {
-		return fmt.Errorf("Unable to create button: %s", err)
+	if !ok {
+		for _, row := range rows(model, nil, nil) {
+			if !isGroup(model, row) {
+				iter, ok = row, true
+				break
+			}
+		}
+		if !ok {
+			return nil // nothing matches the search
+		}
+		c.selection.HandlerBlock(c.changed)
+		if path, err := model.GetPath(iter); err == nil {
+			c.view.ExpandToPath(path)
+		}
+		c.selection.SelectIter(iter)
+		c.selection.HandlerUnblock(c.changed)
 	}
-	_, err = btn.Connect("clicked", func() { c.window.Destroy() })
+	str, err := nameAt(model, iter)
 	if err != nil {
-		return fmt.Errorf("Unable to connect button: %s", err)
+		return err
 	}
-	c.window.Add(btn)
-	c.window.ShowAll()
-	if msg == "this is a test" {
+	if err = c.switchTo(str); err == errCancelled {
 		return nil
+	} else if err != nil {
+		return err
+	}
+	if !permanent {
+		fmt.Println(c.profiles.curr)
+		c.window.Destroy()
 	}
-	gtk.Main()
 	return nil
 }
 
+// keyPressed handles the keys of the popup independent of the focused widget: Esc closes the
+// popup without changing the default profile, the arrow keys move through the profiles while
+// the search entry keeps the focus.
+func (c *chooser) keyPressed(key uint) bool {
+	switch key {
+	case gdk.KEY_Escape:
+		c.window.Destroy()
+	case gdk.KEY_Down:
+		c.highlight(true)
+	case gdk.KEY_Up:
+		c.highlight(false)
+	default:
+		return false
+	}
+	return true
+}
+
 func (c *chooser) setupWindow() {
 	if c.err != nil {
 		return
 	}
-	if c.window, c.err = gtk.WindowNew(gtk.WINDOW_POPUP); c.err != nil {
+	// a toplevel window without decorations instead of a popup window, which would not get the
+	// keyboard focus for the search
+	if c.window, c.err = gtk.WindowNew(gtk.WINDOW_TOPLEVEL); c.err != nil {
 		return
 	}
 	c.window.SetTitle(appName)
+	c.window.SetDecorated(false)
+	c.window.SetKeepAbove(true)
+	c.window.SetSkipTaskbarHint(true)
+	c.window.SetTypeHint(gdk.WINDOW_TYPE_HINT_POPUP_MENU)
 	if _, c.err = c.window.Connect("destroy", gtk.MainQuit); c.err != nil {
 		return
 	}
 	c.window.SetPosition(gtk.WIN_POS_MOUSE)
-	// need this workaround to support also single click to close the Popup;
-	// lost Focus or button-release-event not available for TreeViewNewSelection
-	if !permanent {
-		_, c.err = c.window.ConnectAfter("button-release-event", func() {
-			fmt.Println(c.profiles.curr)
-			c.window.Destroy()
-		})
+}
+
+func (c *chooser) setupKeys() {
+	if c.err != nil {
+		return
+	}
+	_, c.err = c.window.Connect("key-press-event", func(_ *gtk.Window, ev *gdk.Event) bool {
+		return c.keyPressed(gdk.EventKeyNewFromEvent(ev).KeyVal())
+	})
+}
+
+func (c *chooser) setupSearch() {
+	if c.err != nil {
+		return
+	}
+	if c.search, c.err = gtk.SearchEntryNew(); c.err != nil {
+		return
+	}
+	c.search.SetPlaceholderText("Search profiles")
+	if _, c.err = c.search.Connect("search-changed", func() {
+		if err := c.applySearch(c.query()); err != nil {
+			log.Println(err)
+		}
+	}); c.err != nil {
+		return
 	}
+	_, c.err = c.search.Connect("activate", func() {
+		if err := c.activate(); err != nil {
+			c.showError("Unable to switch the profile", err, c.activate)
+		}
+	})
+}
+
+func (c *chooser) setupScroll() {
+	if c.err != nil {
+		return
+	}
+	if c.scroll, c.err = gtk.ScrolledWindowNew(nil, nil); c.err != nil {
+		return
+	}
+	c.scroll.SetPolicy(gtk.POLICY_NEVER, gtk.POLICY_AUTOMATIC)
+	// grow with the list up to the maximum height (GTK 3.22)
+	if c.err = c.scroll.SetProperty("propagate-natural-height", true); c.err != nil {
+		return
+	}
+	c.err = c.scroll.SetProperty("max-content-height", maxListHeight)
 }
 
 func (c *chooser) setupRootBox() {
@@ -131,81 +360,356 @@ func (c *chooser) setupSelection() {
 		return
 	}
 	c.selection.SetMode(gtk.SELECTION_SINGLE)
-	path, err := gtk.TreePathNewFromString(fmt.Sprintf("%d", c.profiles.currIdx))
-	if err != nil {
-		c.err = err
+	if c.profiles.currIdx < 0 {
+		c.err = fmt.Errorf("invalid index %d of the current profile", c.profiles.currIdx)
 		return
 	}
-	c.selection.SelectPath(path)
-	c.view.RowActivated(path, c.view.GetColumn(0))
-	_, c.err = c.selection.Connect("changed", func() error { return c.selectionChanged() })
+	if c.err = c.selectCurrent(); c.err != nil {
+		return
+	}
+	c.changed, c.err = c.selection.Connect("changed", func() {
+		if err := c.selectionChanged(); err != nil {
+			c.showError("Unable to switch the profile", err, c.selectionChanged)
+		}
+	})
 }
 
 func (c *chooser) setupTreeView() {
 	if c.err != nil {
 		return
 	}
-	if c.view, c.err = gtk.TreeViewNewWithModel(c.store); c.err != nil {
+	if c.store == nil {
+		c.err = fmt.Errorf("the tree store is not set up")
 		return
 	}
-	r, err := gtk.CellRendererTextNew()
-	if err != nil {
-		c.err = err
+	if c.filter, c.err = c.store.FilterNew(nil); c.err != nil {
 		return
 	}
-	column, err := gtk.TreeViewColumnNewWithAttribute(columnTitle, r, "text", 0)
-	if err != nil {
-		c.err = err
+	c.filter.SetVisibleColumn(columnVisible)
+	if c.view, c.err = gtk.TreeViewNewWithModel(c.filter); c.err != nil {
+		return
+	}
+	// typing goes into the search entry instead of the built-in search of the tree view
+	c.view.SetEnableSearch(false)
+	c.view.SetShowExpanders(true)
+	c.view.SetCanFocus(false)
+	if c.err = c.setupColumns(); c.err != nil {
 		return
 	}
-	c.view.AppendColumn(column)
+	// need this workaround to support also single click to close the Popup;
+	// lost Focus or button-release-event not available for TreeViewNewSelection. The handler
+	// runs before the one of the view, which stops the event. The context menu and clicks on groups
+	// keep the popup open.
+	if !permanent {
+		_, c.err = c.view.Connect("button-release-event", func(_ *gtk.TreeView, ev *gdk.Event) {
+			btn := gdk.EventButtonNewFromEvent(ev)
+			if btn.Button() != primaryButton {
+				return
+			}
+			if iter, ok := c.rowAtPos(int(btn.X()), int(btn.Y())); ok && isGroup(&c.filter.TreeModel, iter) {
+				return
+			}
+			fmt.Println(c.profiles.curr)
+			c.window.Destroy()
+		})
+	}
 }
 
-func (c *chooser) setupListStore() {
+// setupColumns adds the columns of the tree view: the environment colour, the type icon, the name,
+// the region, the masked key ID and the expiry countdown. Every cell of a production profile gets
+// the background of its row.
+func (c *chooser) setupColumns() error {
+	columns := []struct {
+		title     string
+		attribute string
+		column    int
+	}{
+		{"", "background", columnColor},
+		{"", "icon-name", columnIcon},
+		{columnTitle, "text", columnName},
+		{"Region", "text", columnRegion},
+		{"Key ID", "text", columnKeyID},
+		{"Expires", "text", columnExpiry},
+	}
+	for _, col := range columns {
+		r, err := newRenderer(col.column)
+		if err != nil {
+			return err
+		}
+		column, err := gtk.TreeViewColumnNewWithAttribute(col.title, r, col.attribute, col.column)
+		if err != nil {
+			return err
+		}
+		column.AddAttribute(r, "cell-background", columnBackground)
+		c.view.AppendColumn(column)
+	}
+	return nil
+}
+
+// newRenderer returns the cell renderer of the given column of the tree store
+func newRenderer(column int) (gtk.ICellRenderer, error) {
+	switch column {
+	case columnIcon:
+		return gtk.CellRendererPixbufNew()
+	case columnColor:
+		r, err := gtk.CellRendererTextNew()
+		if err != nil {
+			return nil, err
+		}
+		// the background of the text is a small swatch of the colour
+		return r, r.SetProperty("text", "  ")
+	}
+	return gtk.CellRendererTextNew()
+}
+
+// newStore returns an empty tree store with all columns
+func newStore() (*gtk.TreeStore, error) {
+	return gtk.TreeStoreNew(columnTypes...)
+}
+
+func (c *chooser) setupStore() {
 	if c.err != nil {
 		return
 	}
-	if c.store, c.err = gtk.ListStoreNew(glib.TYPE_STRING); c.err != nil {
+	if c.store, c.err = newStore(); c.err != nil {
 		return
 	}
-	for _, i := range c.profiles.list {
-		if c.err = c.store.SetValue(c.store.Append(), 0, i); c.err != nil {
-			return
+	c.err = c.fillStore()
+}
+
+// fillStore adds the groups with their profiles followed by the ungrouped profiles; without a
+// group criterion the list is flat.
+func (c *chooser) fillStore() error {
+	settings, err := awsdefault.GetSettings()
+	if err != nil {
+		log.Println(err) // the profiles are shown without colours and tags
+		settings = nil
+	}
+	group, err := c.newGrouper(settings)
+	if err != nil {
+		return err
+	}
+	names, members, ungrouped := groupProfiles(c.profiles.list, group)
+	for _, g := range names {
+		parent := c.store.Append(nil)
+		if err = setValues(c.store, parent, []int{columnName, columnGroup}, []interface{}{g, true}); err != nil {
+			return err
+		}
+		for _, n := range members[g] {
+			if err = c.appendProfile(parent, n, settings); err != nil {
+				return err
+			}
 		}
 	}
+	for _, n := range ungrouped {
+		if err = c.appendProfile(nil, n, settings); err != nil {
+			return err
+		}
+	}
+	_, err = c.filterRows(nil, c.query())
+	return err
+}
+
+// appendProfile adds a row of the profile below the parent
+func (c *chooser) appendProfile(parent *gtk.TreeIter, name string, settings *awsdefault.Settings) error {
+	iter := c.store.Append(parent)
+	if err := c.store.SetValue(iter, columnName, name); err != nil {
+		return err
+	}
+	if name == noProfile {
+		return nil
+	}
+	return c.setDetails(iter, name, settings)
+}
+
+// setDetails fills the columns describing the profile in the given row. Unset columns stay empty,
+// which leaves the colour and the background of the row untouched; so does a profile, which cannot
+// be read.
+func (c *chooser) setDetails(iter *gtk.TreeIter, name string, settings *awsdefault.Settings) error {
+	p, err := c.profiles.file.GetProfileBy(name)
+	if err != nil {
+		log.Println(err)
+		return nil
+	}
+	info := p.Info(name, false)
+	columns := []int{columnIcon, columnRegion, columnKeyID}
+	values := []interface{}{typeIcons[info.Type], info.Region, info.KeyID}
+	if info.Expiry != nil {
+		columns = append(columns, columnExpiresAt, columnExpiry)
+		values = append(values, info.Expiry.Unix(), awsdefault.Countdown(*info.Expiry, time.Now()))
+	}
+	if settings != nil {
+		if color := settings.Color(name); len(color) > 0 {
+			columns = append(columns, columnColor)
+			values = append(values, color)
+		}
+		if settings.IsProduction(name) {
+			columns = append(columns, columnBackground)
+			values = append(values, productionBackground)
+		}
+	}
+	return setValues(c.store, iter, columns, values)
+}
+
+// updateCountdowns recalculates the time left for all profiles with temporary credentials; the
+// result keeps the timer running
+func (c *chooser) updateCountdowns() bool {
+	now := time.Now()
+	for _, iter := range rows(&c.store.TreeModel, nil, nil) {
+		value, err := c.store.GetValue(iter, columnExpiresAt)
+		if err != nil {
+			log.Println(err)
+			return true
+		}
+		v, err := value.GoValue()
+		if err != nil {
+			log.Println(err)
+			return true
+		}
+		if expiry, ok := v.(int64); ok && expiry > 0 {
+			if err = c.store.SetValue(iter, columnExpiry, awsdefault.Countdown(time.Unix(expiry, 0), now)); err != nil {
+				log.Println(err)
+			}
+		}
+	}
+	return true
+}
+
+// selectCurrent expands the group of the current default profile and selects the profile, if the
+// search does not hide it
+func (c *chooser) selectCurrent() error {
+	iter, err := c.expandTo(c.profiles.curr)
+	if err != nil {
+		return err
+	}
+	if iter == nil {
+		c.selection.UnselectAll()
+		return nil
+	}
+	c.selection.SelectIter(iter)
+	return nil
+}
+
+// refresh shows the profiles of the reloaded credentials file and selects the current default
+// profile again, keeping the search. The selection handler is blocked, otherwise the file would
+// be written again.
+func (c *chooser) refresh(file *awsdefault.CredentialsFile) error {
+	c.profiles.reload(file)
+	c.selection.HandlerBlock(c.changed)
+	defer c.selection.HandlerUnblock(c.changed)
+	c.store.Clear()
+	if err := c.fillStore(); err != nil {
+		return err
+	}
+	if len(c.query()) > 0 {
+		c.view.ExpandAll()
+	}
+	return c.selectCurrent()
+}
+
+// stored shows the profiles after the chooser changed the credentials file itself; a running
+// awsdefaultd reads the file first, so its list and its next switch contain the change
+func (c *chooser) stored() error {
+	if err := c.profiles.sync(); err != nil {
+		return err
+	}
+	return c.refresh(c.profiles.source)
+}
+
+// watch refreshes the chooser whenever the credentials file or the settings get changed by another
+// tool
+func (c *chooser) watch() error {
+	events, err := c.profiles.source.Watch(nil) // runs as long as the application
+	if err != nil {
+		return err
+	}
+	go func() {
+		for e := range events {
+			if e.Err != nil {
+				log.Println(e.Err)
+				continue
+			}
+			file := e.File
+			_, _ = glib.IdleAdd(func() {
+				if err := c.refresh(file); err != nil {
+					log.Println(err)
+				}
+			})
+		}
+	}()
+	return nil
 }
 
 func initializeChooser(p *profiles) (c *chooser, err error) {
 	c = &chooser{profiles: p}
-	c.setupListStore()
+	c.setupStore()
 	c.setupTreeView()
+	c.setupContextMenu()
 	c.setupSelection()
+	c.setupSearch()
+	c.setupScroll()
 	c.setupRootBox()
 	c.setupWindow()
+	c.setupKeys()
 	if c.err != nil {
 		return nil, c.err
 	}
-	c.box.PackStart(c.view, true, true, 0)
+	c.scroll.Add(c.view)
+	c.box.PackStart(c.search, false, false, 0)
+	c.box.PackStart(c.scroll, true, true, 0)
 	c.window.Add(c.box)
+	c.search.GrabFocus()
+	if _, err = glib.TimeoutAdd(countdownInterval, c.updateCountdowns); err != nil {
+		return nil, err
+	}
 	return c, nil
 }
 
 func fetchProfiles() (p *profiles, err error) {
 	p = new(profiles)
-	if p.file, err = awsdefault.GetCredentialsFile(); err != nil {
+	if p.source, err = awsdefault.GetCredentialsFile(); err != nil {
 		return
 	}
+	p.file = awsdefault.NewSwitcher(p.source)
+	p.update()
+	return p, nil
+}
+
+// reload replaces the credentials file by its reloaded version and updates the profiles. Without
+// awsdefaultd the switcher is built again, because the mode may have changed, too.
+func (p *profiles) reload(file *awsdefault.CredentialsFile) {
+	p.source = file
+	if _, ok := p.file.(*awsdefault.Client); !ok {
+		p.file = awsdefault.LocalSwitcher(file)
+	}
+	p.update()
+}
+
+// sync lets a running awsdefaultd read the credentials file again, after it was changed via the
+// source; without the daemon the switcher works on the source itself
+func (p *profiles) sync() error {
+	if c, ok := p.file.(*awsdefault.Client); ok {
+		return c.Reload()
+	}
+	return nil
+}
+
+// update reads the list of profiles and the current default profile
+func (p *profiles) update() {
+	var err error
 	p.list = append(p.file.GetProfilesNames(), noProfile)
 	p.curr, p.currIdx, err = p.file.GetUsedProfileNameAndIndex()
 	if err != nil || p.currIdx == -2 { // -2 means no default set
 		p.curr = noProfile
 		p.currIdx = len(p.list) - 1 // last item is noProfile
 	}
-	return p, nil
 }
 
 func init() {
 	flag.BoolVar(&permanent, "permanent", false, "the popup will not be closed after you clicked on a profile")
+	flag.StringVar(&stsEndpoint, "sts-endpoint", "", "the AWS STS endpoint used for profiles with MFA (default: sts_endpoint of the settings or "+awsdefault.DefaultSTSEndpoint+")")
+	flag.StringVar(&groupBy, "group", "", "group the profiles by "+awsdefault.GroupByTag+", "+awsdefault.GroupByAccount+" or name "+awsdefault.GroupByPrefix+" (default: group_by of the settings)")
+	flag.BoolVar(&indicate, "indicator", false, "show the current profile in the tray (StatusNotifierItem) instead of the popup")
 	flag.Parse()
 }
 
@@ -213,16 +717,35 @@ func main() {
 	gtk.Init(&os.Args)
 	p, err := fetchProfiles()
 	if err != nil { // only profile related errors
-		if e := showError(err.Error()); e != nil {
-			log.Println(e)
+		showError(nil, failure{
+			summary: "Unable to read the AWS credentials file",
+			err:     err,
+			retry: func() error {
+				p, err = fetchProfiles()
+				return err
+			},
+			file: p.source,
+		})
+		if err != nil {
+			log.Fatalln(err)
 		}
-		log.Fatalln(err)
+	}
+	if indicate {
+		if err = runIndicator(p); err != nil {
+			log.Fatalln(err)
+		}
+		return
 	}
 
 	c, err := initializeChooser(p)
 	if err != nil {
 		log.Fatalln(err)
 	}
+	if permanent {
+		if err = c.watch(); err != nil {
+			log.Println(err)
+		}
+	}
 	c.window.ShowAll()
 	gtk.Main()
 }
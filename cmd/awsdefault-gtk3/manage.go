@@ -0,0 +1,384 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/gotk3/gotk3/gdk"
+	"github.com/gotk3/gotk3/gtk"
+	"github.com/peterbueschel/awsdefault"
+)
+
+// mouse buttons
+const (
+	primaryButton   = 1
+	secondaryButton = 3
+)
+
+type (
+	// profileForm holds the input of the profile dialog
+	profileForm struct {
+		name, keyID, secret, token, region string
+	}
+
+	// menuAction is an entry of the context menu; an action without run is a separator
+	menuAction struct {
+		label string
+		run   func() error
+	}
+)
+
+// profile validates the input and returns the profile described by it
+func (f profileForm) profile() (*awsdefault.Profile, error) {
+	p := &awsdefault.Profile{
+		AccessKeyID:     strings.TrimSpace(f.keyID),
+		SecretAccessKey: awsdefault.Secret(strings.TrimSpace(f.secret)),
+		SessionToken:    awsdefault.Secret(strings.TrimSpace(f.token)),
+		Region:          strings.TrimSpace(f.region),
+	}
+	if err := awsdefault.ValidateKeys(p); err != nil {
+		return nil, err
+	}
+	if len(p.Region) > 0 {
+		if _, err := awsdefault.GetRegion(p.Region); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// formOf returns the form filled with the given profile
+func (c *chooser) formOf(name string) (profileForm, error) {
+	p, err := c.profiles.source.GetProfileBy(name)
+	if err != nil {
+		return profileForm{}, err
+	}
+	return profileForm{
+		name:   name,
+		keyID:  p.AccessKeyID,
+		secret: p.SecretAccessKey.Reveal(),
+		token:  p.SessionToken.Reveal(),
+		region: p.Region,
+	}, nil
+}
+
+// addProfile stores a new profile; existing profiles are never replaced
+func (c *chooser) addProfile(f profileForm) error {
+	p, err := f.profile()
+	if err != nil {
+		return err
+	}
+	if err = c.profiles.source.AddProfile(strings.TrimSpace(f.name), p, false); err != nil {
+		return err
+	}
+	return c.stored()
+}
+
+// editProfile replaces the keys and the region of an existing profile
+func (c *chooser) editProfile(f profileForm) error {
+	p, err := f.profile()
+	if err != nil {
+		return err
+	}
+	if err = c.profiles.source.UpdateProfile(f.name, p); err != nil {
+		return err
+	}
+	return c.stored()
+}
+
+// duplicateProfile stores a copy of the profile under a new name
+func (c *chooser) duplicateProfile(name, newName string) error {
+	if err := c.profiles.source.CopyProfile(name, strings.TrimSpace(newName)); err != nil {
+		return err
+	}
+	return c.stored()
+}
+
+// renameProfile moves the profile to a new name
+func (c *chooser) renameProfile(name, newName string) error {
+	if err := c.profiles.source.RenameProfile(name, strings.TrimSpace(newName)); err != nil {
+		return err
+	}
+	return c.stored()
+}
+
+// deleteProfile removes the profile
+func (c *chooser) deleteProfile(name string) error {
+	if err := c.profiles.source.DeleteProfile(name); err != nil {
+		return err
+	}
+	return c.stored()
+}
+
+// copyKeyID puts the AWS_ACCESS_KEY_ID of the profile into the clipboard
+func (c *chooser) copyKeyID(name string) error {
+	p, err := c.profiles.source.GetProfileBy(name)
+	if err != nil {
+		return err
+	}
+	clipboard, err := gtk.ClipboardGet(gdk.SELECTION_CLIPBOARD)
+	if err != nil {
+		return err
+	}
+	clipboard.SetText(p.AccessKeyID)
+	return nil
+}
+
+// rowAtPos returns the row at the given position of the tree view
+func (c *chooser) rowAtPos(x, y int) (*gtk.TreeIter, bool) {
+	path := new(gtk.TreePath)
+	if !c.view.GetPathAtPos(x, y, path, nil, nil, nil) {
+		return nil, false
+	}
+	iter, err := c.filter.GetIter(path)
+	return iter, err == nil
+}
+
+// nameAtPos returns the name of the profile in the row at the given position of the tree view;
+// empty if there is no profile
+func (c *chooser) nameAtPos(x, y int) string {
+	iter, ok := c.rowAtPos(x, y)
+	if !ok || isGroup(&c.filter.TreeModel, iter) {
+		return ""
+	}
+	name, err := nameAt(&c.filter.TreeModel, iter)
+	if err != nil || name == noProfile {
+		return ""
+	}
+	return name
+}
+
+// menuActions returns the entries of the context menu of the given profile; without a profile
+// only a new profile can be added
+func (c *chooser) menuActions(name string) []menuAction {
+	var actions []menuAction
+	if len(name) > 0 {
+		actions = []menuAction{
+			{"Edit…", func() error { return c.runProfileDialog(name) }},
+			{"Duplicate…", func() error {
+				return c.runNameDialog("Duplicate "+name, "Duplicate", name+"-copy", func(n string) error {
+					return c.duplicateProfile(name, n)
+				})
+			}},
+			{"Rename…", func() error {
+				return c.runNameDialog("Rename "+name, "Rename", name, func(n string) error {
+					return c.renameProfile(name, n)
+				})
+			}},
+			{"Delete…", func() error { return c.confirmDelete(name) }},
+			{"Copy key ID", func() error { return c.copyKeyID(name) }},
+			{},
+		}
+	}
+	return append(actions, menuAction{"Add profile…", func() error { return c.runProfileDialog("") }})
+}
+
+// showContextMenu opens the context menu of the profile under the pointer on a right click. The
+// click neither selects nor switches to the profile.
+func (c *chooser) showContextMenu(ev *gdk.Event) bool {
+	btn := gdk.EventButtonNewFromEvent(ev)
+	if btn.Button() != secondaryButton {
+		return false
+	}
+	menu, err := gtk.MenuNew()
+	if err != nil {
+		log.Println(err)
+		return true
+	}
+	for _, a := range c.menuActions(c.nameAtPos(int(btn.X()), int(btn.Y()))) {
+		if a.run == nil {
+			sep, err := gtk.SeparatorMenuItemNew()
+			if err != nil {
+				log.Println(err)
+				return true
+			}
+			menu.Append(sep)
+			continue
+		}
+		item, err := gtk.MenuItemNewWithLabel(a.label)
+		if err != nil {
+			log.Println(err)
+			return true
+		}
+		label, run := a.label, a.run
+		if _, err = item.Connect("activate", func() {
+			if err := run(); err != nil {
+				c.showError(fmt.Sprintf("Unable to %s", strings.ToLower(strings.TrimSuffix(label, "…"))), err, run)
+			}
+		}); err != nil {
+			log.Println(err)
+			return true
+		}
+		menu.Append(item)
+	}
+	c.menu = menu // keeps the menu alive while it is shown
+	menu.ShowAll()
+	menu.PopupAtPointer(ev)
+	return true
+}
+
+func (c *chooser) setupContextMenu() {
+	if c.err != nil {
+		return
+	}
+	_, c.err = c.view.Connect("button-press-event", func(_ *gtk.TreeView, ev *gdk.Event) bool {
+		return c.showContextMenu(ev)
+	})
+}
+
+// newDialog returns a modal dialog above the chooser with the buttons Cancel and accept, and its
+// content area
+func (c *chooser) newDialog(title, accept string) (*gtk.Dialog, *gtk.Box, error) {
+	d, err := gtk.DialogNew()
+	if err != nil {
+		return nil, nil, err
+	}
+	d.SetTitle(title)
+	d.SetTransientFor(c.window)
+	d.SetModal(true)
+	if _, err = d.AddButton("Cancel", gtk.RESPONSE_CANCEL); err != nil {
+		return nil, nil, err
+	}
+	if _, err = d.AddButton(accept, gtk.RESPONSE_OK); err != nil {
+		return nil, nil, err
+	}
+	d.SetDefaultResponse(gtk.RESPONSE_OK)
+	box, err := d.GetContentArea()
+	if err != nil {
+		return nil, nil, err
+	}
+	box.SetSpacing(6)
+	box.SetBorderWidth(12)
+	return d, box, nil
+}
+
+// runDialog shows the dialog until apply succeeds or the dialog is cancelled; errors of apply are
+// shown inside the dialog
+func runDialog(d *gtk.Dialog, box *gtk.Box, apply func() error) error {
+	msg, err := gtk.LabelNew("")
+	if err != nil {
+		return err
+	}
+	msg.SetLineWrap(true)
+	msg.SetSelectable(true)
+	box.PackEnd(msg, false, false, 0)
+	d.ShowAll()
+	defer d.Destroy()
+	for d.Run() == gtk.RESPONSE_OK {
+		if err = apply(); err == nil {
+			return nil
+		}
+		msg.SetText(err.Error())
+	}
+	return nil
+}
+
+// newEntry returns an entry with the text, which activates the default button of the dialog
+func newEntry(text string, visible bool) (*gtk.Entry, error) {
+	e, err := gtk.EntryNew()
+	if err != nil {
+		return nil, err
+	}
+	e.SetText(text)
+	e.SetVisibility(visible)
+	e.SetActivatesDefault(true)
+	e.SetHExpand(true)
+	return e, nil
+}
+
+// entryText returns the text of the entry
+func entryText(e *gtk.Entry) string {
+	s, _ := e.GetText()
+	return s
+}
+
+// runProfileDialog shows the dialog to add a new profile or, if a name is given, to edit the keys
+// and the region of the profile
+func (c *chooser) runProfileDialog(name string) error {
+	form, title, accept := profileForm{}, "Add profile", "Add"
+	if len(name) > 0 {
+		var err error
+		if form, err = c.formOf(name); err != nil {
+			return err
+		}
+		title, accept = "Edit "+name, "Save"
+	}
+	d, box, err := c.newDialog(title, accept)
+	if err != nil {
+		return err
+	}
+	grid, err := gtk.GridNew()
+	if err != nil {
+		return err
+	}
+	grid.SetRowSpacing(6)
+	grid.SetColumnSpacing(12)
+	fields := []struct {
+		label   string
+		value   string
+		visible bool
+	}{
+		{"Name", form.name, true},
+		{"Access key ID", form.keyID, true},
+		{"Secret access key", form.secret, false},
+		{"Session token", form.token, false},
+		{"Region", form.region, true},
+	}
+	entries := make([]*gtk.Entry, len(fields))
+	for i, f := range fields {
+		l, err := gtk.LabelNew(f.label)
+		if err != nil {
+			return err
+		}
+		l.SetXAlign(0)
+		if entries[i], err = newEntry(f.value, f.visible); err != nil {
+			return err
+		}
+		grid.Attach(l, 0, i, 1, 1)
+		grid.Attach(entries[i], 1, i, 1, 1)
+	}
+	entries[0].SetSensitive(len(name) == 0) // profiles get renamed via the context menu
+	box.PackStart(grid, true, true, 0)
+	return runDialog(d, box, func() error {
+		f := profileForm{
+			name:   entryText(entries[0]),
+			keyID:  entryText(entries[1]),
+			secret: entryText(entries[2]),
+			token:  entryText(entries[3]),
+			region: entryText(entries[4]),
+		}
+		if len(name) > 0 {
+			return c.editProfile(f)
+		}
+		return c.addProfile(f)
+	})
+}
+
+// runNameDialog asks for a new name of a profile and passes it to apply
+func (c *chooser) runNameDialog(title, accept, name string, apply func(string) error) error {
+	d, box, err := c.newDialog(title, accept)
+	if err != nil {
+		return err
+	}
+	e, err := newEntry(name, true)
+	if err != nil {
+		return err
+	}
+	box.PackStart(e, true, true, 0)
+	return runDialog(d, box, func() error { return apply(entryText(e)) })
+}
+
+// confirmDelete deletes the profile after a confirmation, which warns about production profiles
+func (c *chooser) confirmDelete(name string) error {
+	d := gtk.MessageDialogNew(c.window, gtk.DIALOG_MODAL, gtk.MESSAGE_QUESTION, gtk.BUTTONS_YES_NO,
+		"Delete the profile %s?", name)
+	defer d.Destroy()
+	if s, err := awsdefault.GetSettings(); err == nil && s.IsProduction(name) {
+		d.FormatSecondaryText("%s is tagged as a production profile.", name)
+	}
+	if d.Run() != gtk.RESPONSE_YES {
+		return nil
+	}
+	return c.deleteProfile(name)
+}
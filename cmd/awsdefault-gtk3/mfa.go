@@ -0,0 +1,100 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/gotk3/gotk3/gtk"
+	"github.com/peterbueschel/awsdefault"
+)
+
+// sessionMargin is the minimal remaining lifetime of session credentials to be used without
+// asking for a new MFA code
+const sessionMargin = 5 * time.Minute
+
+var (
+	stsEndpoint string
+
+	// errCancelled is returned, when the user cancelled the switch to a profile
+	errCancelled = errors.New("cancelled by the user")
+)
+
+// newSTS returns the client of the STS endpoint given via flag, the settings or the default one
+func newSTS() *awsdefault.STS {
+	endpoint := stsEndpoint
+	if len(endpoint) == 0 {
+		if s, err := awsdefault.GetSettings(); err == nil {
+			endpoint = s.STSEndpoint()
+		}
+	}
+	return awsdefault.NewSTS(endpoint)
+}
+
+// resolveMFA returns the profile to switch to: the given profile or, if it requires MFA, its
+// session profile. Without valid session credentials, the code of the MFA device is asked for.
+func (c *chooser) resolveMFA(name string) (string, error) {
+	p, err := c.profiles.source.GetProfileBy(name)
+	if err != nil || len(p.MFASerial()) == 0 {
+		return name, nil // a missing profile is reported by the switch itself
+	}
+	if session, ok := c.profiles.source.ValidSession(name, sessionMargin); ok {
+		return session, nil
+	}
+	return c.askMFACode(name)
+}
+
+// startSession requests session credentials for the profile with the code of its MFA device and
+// stores them in the session profile, whose name is returned. A running awsdefaultd reads the
+// stored session before the switch to it.
+func (c *chooser) startSession(name, code string) (string, error) {
+	p, err := c.profiles.source.GetProfileBy(name)
+	if err != nil {
+		return "", err
+	}
+	session, err := newSTS().GetSessionToken(p, strings.TrimSpace(code))
+	if err != nil {
+		return "", err
+	}
+	n, err := c.profiles.source.StoreSession(name, session)
+	if err != nil {
+		return "", err
+	}
+	return n, c.profiles.sync()
+}
+
+// askMFACode shows the dialog asking for the code of the MFA device of the profile until the
+// session credentials were obtained or the dialog is cancelled; a wrong code is shown inside the
+// dialog.
+func (c *chooser) askMFACode(name string) (string, error) {
+	d, box, err := c.newDialog("MFA code for "+name, "Continue")
+	if err != nil {
+		return "", err
+	}
+	l, err := gtk.LabelNew("Enter the current code of the MFA device of " + name + ".")
+	if err != nil {
+		return "", err
+	}
+	l.SetXAlign(0)
+	e, err := newEntry("", true)
+	if err != nil {
+		return "", err
+	}
+	e.SetMaxLength(6)
+	e.SetInputPurpose(gtk.INPUT_PURPOSE_DIGITS)
+	box.PackStart(l, false, false, 0)
+	box.PackStart(e, false, false, 0)
+	var session string
+	err = runDialog(d, box, func() error {
+		var err error
+		session, err = c.startSession(name, entryText(e))
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(session) == 0 {
+		return "", errCancelled
+	}
+	return session, nil
+}
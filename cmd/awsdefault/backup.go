@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"text/tabwriter"
+
+	"github.com/peterbueschel/awsdefault"
+	"github.com/urfave/cli"
+)
+
+func backupProfiles() *cli.Command {
+	return &cli.Command{
+		Name:      "backup",
+		Usage:     "Writes the credentials, the AWS config and the awsdefault settings into a passphrase-encrypted file.",
+		ArgsUsage: "<file>",
+		Flags: []cli.Flag{
+			cli.BoolFlag{
+				Name:  "force, y",
+				Usage: "overwrite an existing file without asking",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			path := c.Args().First()
+			if len(path) == 0 {
+				return fmt.Errorf("the path of the backup file is required")
+			}
+			if _, err := os.Stat(path); err == nil && !c.Bool("force") {
+				if !confirm(os.Stdin, os.Stderr, fmt.Sprintf("%s already exists; overwrite it?", path)) {
+					return fmt.Errorf("backup aborted")
+				}
+			}
+			b, err := awsdefault.CreateBackup()
+			if err != nil {
+				return err
+			}
+			passphrase, err := readPassphrase(true)
+			if err != nil {
+				return err
+			}
+			data, err := b.Encrypt(passphrase)
+			if err != nil {
+				return err
+			}
+			return ioutil.WriteFile(path, data, 0600)
+		},
+	}
+}
+
+// writeRestoreReport prints the results of a restore as table
+func writeRestoreReport(w io.Writer, results []awsdefault.RestoreResult) {
+	t := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(t, "FILE\tSECTION\tACTION")
+	conflicts := 0
+	for _, r := range results {
+		fmt.Fprintf(t, "%s\t%s\t%s\n", r.File, r.Section, r.Action)
+		if r.Action == awsdefault.RestoreConflict {
+			conflicts++
+		}
+	}
+	t.Flush()
+	if conflicts > 0 {
+		fmt.Fprintf(w, "\n%d conflicting sections kept as they are; use --overwrite to replace them\n", conflicts)
+	}
+}
+
+func restoreProfiles() *cli.Command {
+	return &cli.Command{
+		Name:      "restore",
+		Usage:     "Merges a backup into the local files and reports conflicts.",
+		ArgsUsage: "<file>",
+		Flags: []cli.Flag{
+			cli.BoolFlag{
+				Name:  "dry-run, n",
+				Usage: "only report what would change",
+			},
+			cli.BoolFlag{
+				Name:  "overwrite",
+				Usage: "replace local sections conflicting with the backup",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			out, err := newPrinter(c)
+			if err != nil {
+				return err
+			}
+			if c.NArg() < 1 {
+				return fmt.Errorf("the path of the backup file is required")
+			}
+			data, err := ioutil.ReadFile(c.Args().First())
+			if err != nil {
+				return err
+			}
+			passphrase, err := readPassphrase(false)
+			if err != nil {
+				return err
+			}
+			b, err := awsdefault.DecryptBackup(data, passphrase)
+			if err != nil {
+				return err
+			}
+			results, err := b.Restore(c.Bool("dry-run"), c.Bool("overwrite"))
+			if err != nil {
+				return err
+			}
+			return out.print(results, func(w io.Writer) { writeRestoreReport(w, results) })
+		},
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-ini/ini"
+	"github.com/peterbueschel/awsdefault"
+)
+
+func Test_writeRestoreReport(t *testing.T) {
+	var buf bytes.Buffer
+	writeRestoreReport(&buf, []awsdefault.RestoreResult{
+		{File: "credentials", Section: "live", Action: awsdefault.RestoreConflict},
+		{File: "credentials", Section: "dev", Action: awsdefault.RestoreAdded},
+	})
+	if got := buf.String(); !strings.Contains(got, "live     conflict") || !strings.Contains(got, "1 conflicting") {
+		t.Errorf("writeRestoreReport() = %q", got)
+	}
+}
+
+func Test_backupProfiles(t *testing.T) {
+	credentials := "[default]\naws_access_key_id=L\n[live]\naws_access_key_id=L\n"
+	home := tempHome(t, credentials)
+	backup := filepath.Join(home, "backup")
+	t.Setenv("AWSDEFAULT_PASSPHRASE", "secret")
+	if _, err := runApp(t, home, "backup", backup); err != nil {
+		t.Fatalf("backup error = %v", err)
+	}
+	data, err := ioutil.ReadFile(backup)
+	if err != nil {
+		t.Fatalf("could not read the backup: %v", err)
+	}
+	if strings.Contains(string(data), "live") {
+		t.Errorf("backup wrote the profiles unencrypted")
+	}
+}
+
+func Test_restoreProfiles(t *testing.T) {
+	credentials := "[default]\naws_access_key_id=L\n[live]\naws_access_key_id=L\n"
+	backup := filepath.Join(tempHome(t, ""), "backup")
+	t.Setenv("AWSDEFAULT_PASSPHRASE", "secret")
+	if _, err := runApp(t, tempHome(t, credentials), "backup", backup); err != nil {
+		t.Fatalf("backup error = %v", err)
+	}
+
+	fresh := tempHome(t, "")
+	t.Setenv("AWSDEFAULT_PASSPHRASE", "wrong")
+	if _, err := runApp(t, fresh, "restore", backup); err == nil {
+		t.Errorf("restore with a wrong passphrase got no error")
+	}
+	if _, err := os.Stat(filepath.Join(fresh, ".aws", "credentials")); !os.IsNotExist(err) {
+		t.Errorf("restore with a wrong passphrase created the credentials file")
+	}
+
+	t.Setenv("AWSDEFAULT_PASSPHRASE", "secret")
+	out, err := runApp(t, fresh, "--output", "json", "restore", backup)
+	if err != nil {
+		t.Fatalf("restore into a fresh HOME error = %v", err)
+	}
+	var results []awsdefault.RestoreResult
+	if err = json.Unmarshal([]byte(out), &results); err != nil || len(results) != 1 || results[0].Action != awsdefault.RestoreAdded {
+		t.Errorf("restore = %q, %v", out, err)
+	}
+	content, err := ini.Load(filepath.Join(fresh, ".aws", "credentials"))
+	if err != nil {
+		t.Fatalf("could not read the restored credentials file: %v", err)
+	}
+	if got := content.Section("live").Key("aws_access_key_id").String(); got != "L" {
+		t.Errorf("restore live aws_access_key_id = %q, want L", got)
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/peterbueschel/awsdefault"
+	"github.com/urfave/cli"
+)
+
+// completionScripts ask the binary itself for the candidates via the hidden flag
+// --generate-bash-completion of urfave/cli, hence new profiles and tags are completed at once
+var completionScripts = map[string]string{
+	"bash": `_awsdefault() {
+    local cur opts
+    COMPREPLY=()
+    cur="${COMP_WORDS[COMP_CWORD]}"
+    opts=$("${COMP_WORDS[@]:0:$COMP_CWORD}" --generate-bash-completion 2>/dev/null)
+    COMPREPLY=($(compgen -W "${opts}" -- "${cur}"))
+    return 0
+}
+
+complete -o default -F _awsdefault awsdefault
+`,
+	"zsh": `#compdef awsdefault
+
+_awsdefault() {
+    local -a opts
+    opts=(${(f)"$(${words[1,CURRENT-1]} --generate-bash-completion 2>/dev/null)"})
+    if (( ${#opts} )); then
+        compadd -a opts
+    else
+        _files
+    fi
+}
+
+compdef _awsdefault awsdefault
+`,
+	"fish": `function __awsdefault_complete
+    set -l args (commandline -opc)
+    command $args --generate-bash-completion 2>/dev/null
+end
+
+complete -c awsdefault -f -a '(__awsdefault_complete)'
+`,
+}
+
+// completionShells returns the sorted names of the supported shells
+func completionShells() []string {
+	var names []string
+	for n := range completionScripts {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// previousArg returns the word in front of the one to complete
+func previousArg(args []string) string {
+	if len(args) < 2 || args[len(args)-1] != "--"+cli.BashCompletionFlag.GetName() {
+		return ""
+	}
+	return args[len(args)-2]
+}
+
+// completeProfiles completes the name of a profile as the first argument
+func completeProfiles(file awsdefault.Switcher) cli.BashCompleteFunc {
+	return func(c *cli.Context) {
+		if c.NArg() == 0 {
+			writeLines(c.App.Writer, file.GetProfilesNames())
+		}
+	}
+}
+
+// completeTags lists all tags used by any profile
+func completeTags(w io.Writer) {
+	if s, err := awsdefault.GetSettings(); err == nil {
+		writeLines(w, s.AllTags())
+	}
+}
+
+func writeLines(w io.Writer, lines []string) {
+	for _, l := range lines {
+		fmt.Fprintln(w, l)
+	}
+}
+
+func completion() *cli.Command {
+	return &cli.Command{
+		Name:      "completion",
+		Usage:     "Prints the script enabling the completion of commands, profiles and tags in the given shell.",
+		ArgsUsage: "bash|zsh|fish",
+		BashComplete: func(c *cli.Context) {
+			writeLines(c.App.Writer, completionShells())
+		},
+		Action: func(c *cli.Context) error {
+			script, ok := completionScripts[c.Args().First()]
+			if !ok {
+				return fmt.Errorf("unknown shell %q, use one of %v", c.Args().First(), completionShells())
+			}
+			_, err := io.WriteString(os.Stdout, script)
+			return err
+		},
+	}
+}
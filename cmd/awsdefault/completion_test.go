@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/go-ini/ini"
+	"github.com/peterbueschel/awsdefault"
+	"github.com/urfave/cli"
+)
+
+func Test_previousArg(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{
+			name: "0positiv - word in front of the completion flag",
+			args: []string{"awsdefault", "each", "--tag", "--generate-bash-completion"},
+			want: "--tag",
+		},
+		{
+			name: "1negativ - no completion",
+			args: []string{"awsdefault", "each", "--tag"},
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := previousArg(tt.args); got != tt.want {
+				t.Errorf("previousArg() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_completeProfiles(t *testing.T) {
+	content, _ := ini.InsensitiveLoad([]byte("[dev]\naws_access_key_id=A\n[live]\naws_access_key_id=B\n"))
+	file := &awsdefault.CredentialsFile{Content: content}
+	var buf bytes.Buffer
+	app := cli.NewApp()
+	app.Writer = &buf
+	app.EnableBashCompletion = true
+	app.Commands = []cli.Command{*setDefaultProfile(file)}
+
+	app.Run([]string{"awsdefault", "to", "--generate-bash-completion"})
+	if got, want := buf.String(), "dev\nlive\n"; got != want {
+		t.Errorf("completeProfiles() = %q, want %q", got, want)
+	}
+	buf.Reset()
+	app.Run([]string{"awsdefault", "to", "dev", "--generate-bash-completion"})
+	if got := buf.String(); got != "" {
+		t.Errorf("completeProfiles() after the profile = %q, want nothing", got)
+	}
+}
+
+func Test_completion(t *testing.T) {
+	if got := completion(); got == nil {
+		t.Errorf("completion() = %v", got)
+	}
+	for _, s := range completionShells() {
+		if len(completionScripts[s]) == 0 {
+			t.Errorf("completionScripts[%s] is empty", s)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/peterbueschel/awsdefault"
+	"github.com/urfave/cli"
+)
+
+const credentialProcessHint = `add the following lines to your ~/.aws/config:
+
+[default]
+credential_process = awsdefault credential-process`
+
+func credentialProcess(file awsdefault.Switcher) *cli.Command {
+	return &cli.Command{
+		Name:         "credential-process",
+		Usage:        "Returns the credentials of the currently used or a given profile for the credential_process of the AWS SDKs.",
+		ArgsUsage:    "[profile]",
+		BashComplete: completeProfiles(file),
+		Action: func(c *cli.Context) error {
+			p, err := exportProfile(file, c.Args().First())
+			if err != nil {
+				return err
+			}
+			return exportFormats["credential-process"](os.Stdout, p)
+		},
+	}
+}
+
+func switchMode(file *awsdefault.CredentialsFile) *cli.Command {
+	return &cli.Command{
+		Name:      "mode",
+		Usage:     "Shows or changes the mode: 'default' writes the keys into the default profile, 'credential_process' only records the chosen profile.",
+		ArgsUsage: "[default|credential_process]",
+		BashComplete: func(c *cli.Context) {
+			if c.NArg() == 0 {
+				writeLines(c.App.Writer, []string{awsdefault.ModeDefault, awsdefault.ModeCredentialProcess})
+			}
+		},
+		Action: func(c *cli.Context) error {
+			s, err := awsdefault.GetSettings()
+			if err != nil {
+				return err
+			}
+			if c.NArg() < 1 {
+				fmt.Println(s.Mode())
+				return nil
+			}
+			if err = s.SwitchMode(file, c.Args().First()); err != nil {
+				return err
+			}
+			if s.Mode() == awsdefault.ModeCredentialProcess {
+				fmt.Fprintln(os.Stderr, credentialProcessHint)
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/peterbueschel/awsdefault"
+	"github.com/urfave/cli"
+)
+
+// writeFindings prints one line per finding followed by the hint to fix it
+func writeFindings(w io.Writer, findings []awsdefault.Finding) {
+	for _, f := range findings {
+		fmt.Fprintf(w, "%-9s %s\n", "["+f.Severity+"]", f.Message)
+		if len(f.Hint) > 0 {
+			fmt.Fprintf(w, "%-9s fix: %s\n", "", f.Hint)
+		}
+	}
+}
+
+func doctor(file *awsdefault.CredentialsFile) *cli.Command {
+	return &cli.Command{
+		Name:  "doctor",
+		Usage: "Checks the environment, the credentials and the config file; exits with 1 if anything needs to be fixed.",
+		Action: func(c *cli.Context) error {
+			out, err := newPrinter(c)
+			if err != nil {
+				return err
+			}
+			findings := awsdefault.Diagnose(file, os.Environ())
+			if err = out.print(findings, func(w io.Writer) { writeFindings(w, findings) }); err != nil {
+				return err
+			}
+			for _, f := range findings {
+				if f.Severity != awsdefault.SeverityOK {
+					return cli.NewExitError("", 1)
+				}
+			}
+			return nil
+		},
+	}
+}
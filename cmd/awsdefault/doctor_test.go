@@ -0,0 +1,32 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/peterbueschel/awsdefault"
+	"github.com/urfave/cli"
+)
+
+func Test_writeFindings(t *testing.T) {
+	var buf bytes.Buffer
+	writeFindings(&buf, []awsdefault.Finding{
+		{Check: "a", Severity: awsdefault.SeverityOK, Message: "fine"},
+		{Check: "b", Severity: awsdefault.SeverityError, Message: "broken", Hint: "repair it"},
+	})
+	want := "[ok]      fine\n[error]   broken\n          fix: repair it\n"
+	if got := buf.String(); got != want {
+		t.Errorf("writeFindings() = %q, want %q", got, want)
+	}
+}
+
+func Test_doctor(t *testing.T) {
+	out, err := runApp(t, tempHome(t, ""), "doctor")
+	if ee, ok := err.(*cli.ExitError); !ok || ee.ExitCode() != 1 {
+		t.Errorf("doctor in an empty HOME error = %v, want exit code 1", err)
+	}
+	if !strings.Contains(out, "awsdefault import") {
+		t.Errorf("doctor in an empty HOME = %q, want the hint to create the credentials file", out)
+	}
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/peterbueschel/awsdefault"
+	"github.com/urfave/cli"
+)
+
+// states of a command run for one profile
+const (
+	eachOK      = "ok"
+	eachFailed  = "failed"
+	eachTimeout = "timeout"
+	eachError   = "error"
+)
+
+// eachResult describes the run of the command for one profile
+type eachResult struct {
+	Profile  string
+	Status   string
+	Code     int
+	Duration time.Duration
+	Err      error
+}
+
+// prefixWriter writes every complete line with the prefix; concurrent writers share the mutex
+// to keep their lines intact.
+type prefixWriter struct {
+	mu     *sync.Mutex
+	w      io.Writer
+	prefix string
+	buf    []byte
+}
+
+func (p *prefixWriter) Write(b []byte) (int, error) {
+	p.buf = append(p.buf, b...)
+	for {
+		i := bytes.IndexByte(p.buf, '\n')
+		if i < 0 {
+			return len(b), nil
+		}
+		if err := p.writeLine(p.buf[:i+1]); err != nil {
+			return len(b), err
+		}
+		p.buf = p.buf[i+1:]
+	}
+}
+
+// Flush writes the rest of an unterminated last line.
+func (p *prefixWriter) Flush() error {
+	if len(p.buf) == 0 {
+		return nil
+	}
+	err := p.writeLine(append(p.buf, '\n'))
+	p.buf = nil
+	return err
+}
+
+func (p *prefixWriter) writeLine(line []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, err := fmt.Fprintf(p.w, "%s%s", p.prefix, line)
+	return err
+}
+
+// selectProfiles returns all profiles or only those carrying the tag
+func selectProfiles(file awsdefault.Switcher, settings *awsdefault.Settings, tag string) []string {
+	var names []string
+	for _, n := range file.GetProfilesNames() {
+		if len(tag) == 0 || settings.HasTag(n, tag) {
+			names = append(names, n)
+		}
+	}
+	return names
+}
+
+// runEach runs the command once per profile with at most parallel commands at the same time.
+// The output lines are prefixed with the name of the profile. A timeout of zero means no timeout.
+func runEach(file awsdefault.Switcher, names, args []string, parallel int, timeout time.Duration, stdout, stderr io.Writer) []eachResult {
+	if parallel < 1 {
+		parallel = 1
+	}
+	width := 0
+	for _, n := range names {
+		if len(n) > width {
+			width = len(n)
+		}
+	}
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		slots   = make(chan struct{}, parallel)
+		results = make([]eachResult, len(names))
+	)
+	for i, n := range names {
+		i, n := i, n
+		wg.Add(1)
+		slots <- struct{}{}
+		go func() {
+			defer func() { <-slots; wg.Done() }()
+			prefix := fmt.Sprintf("%-*s | ", width, n)
+			out := &prefixWriter{mu: &mu, w: stdout, prefix: prefix}
+			errOut := &prefixWriter{mu: &mu, w: stderr, prefix: prefix}
+			results[i] = runForProfile(file, n, args, timeout, out, errOut)
+			out.Flush()
+			errOut.Flush()
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// runForProfile runs the command with the credentials of one profile
+func runForProfile(file awsdefault.Switcher, name string, args []string, timeout time.Duration, stdout, stderr io.Writer) eachResult {
+	r := eachResult{Profile: name, Status: eachError}
+	p, err := awsdefault.ResolveCredentials(file, name)
+	if err != nil {
+		r.Err = err
+		return r
+	}
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Env = p.Environ(os.Environ())
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+	// processes started by the command may keep the output open after it got killed
+	cmd.WaitDelay = time.Second
+
+	start := time.Now()
+	r.Code, r.Err = exitCode(cmd.Run())
+	r.Duration = time.Since(start)
+	switch {
+	case ctx.Err() == context.DeadlineExceeded:
+		r.Status, r.Err = eachTimeout, nil
+	case r.Err != nil:
+		r.Status = eachError
+	case r.Code != 0:
+		r.Status = eachFailed
+	default:
+		r.Status = eachOK
+	}
+	return r
+}
+
+// writeSummary prints one row per profile and reports whether all commands succeeded
+func writeSummary(w io.Writer, results []eachResult) bool {
+	ok := true
+	t := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(t, "PROFILE\tSTATUS\tEXIT\tDURATION")
+	for _, r := range results {
+		code := fmt.Sprint(r.Code)
+		if r.Status == eachError || r.Status == eachTimeout {
+			code = "-"
+		}
+		fmt.Fprintf(t, "%s\t%s\t%s\t%s", r.Profile, r.Status, code, r.Duration.Round(time.Millisecond))
+		if r.Err != nil {
+			fmt.Fprintf(t, "\t%s", r.Err)
+		}
+		fmt.Fprintln(t)
+		ok = ok && r.Status == eachOK
+	}
+	t.Flush()
+	return ok
+}
+
+func eachCommand(file awsdefault.Switcher) *cli.Command {
+	return &cli.Command{
+		Name:      "each",
+		Aliases:   []string{"foreach"},
+		Usage:     "Runs a command once per profile with the credentials of the profile set as AWS_* environment variables.",
+		ArgsUsage: "[--tag <tag>] [--parallel <n>] [--timeout <duration>] -- <command> [args...]",
+		BashComplete: func(c *cli.Context) {
+			switch previousArg(os.Args) {
+			case "-t", "--tag":
+				completeTags(c.App.Writer)
+			}
+		},
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "tag, t",
+				Usage: "only use the profiles carrying this tag",
+			},
+			cli.IntFlag{
+				Name:  "parallel, p",
+				Value: 4,
+				Usage: "maximum number of commands running at the same time",
+			},
+			cli.DurationFlag{
+				Name:  "timeout",
+				Usage: "kill the command of a profile after this duration, e.g. 30s; no timeout by default",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			args := c.Args()
+			if len(args) < 1 {
+				return fmt.Errorf("a command is required")
+			}
+			settings, err := awsdefault.GetSettings()
+			if err != nil {
+				return err
+			}
+			names := selectProfiles(file, settings, c.String("tag"))
+			if len(names) == 0 {
+				return fmt.Errorf("no profile found")
+			}
+			results := runEach(file, names, args, c.Int("parallel"), c.Duration("timeout"), os.Stdout, os.Stderr)
+			fmt.Fprintln(os.Stderr)
+			if !writeSummary(os.Stderr, results) {
+				return cli.NewExitError("", 1)
+			}
+			return nil
+		},
+	}
+}
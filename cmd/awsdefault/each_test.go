@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-ini/ini"
+	"github.com/kylelemons/godebug/pretty"
+	"github.com/peterbueschel/awsdefault"
+)
+
+func Test_prefixWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := &prefixWriter{mu: &sync.Mutex{}, w: &buf, prefix: "live | "}
+	w.Write([]byte("a\nb"))
+	w.Write([]byte("c\nd"))
+	w.Flush()
+	if got, want := buf.String(), "live | a\nlive | bc\nlive | d\n"; got != want {
+		t.Errorf("prefixWriter = %q, want %q", got, want)
+	}
+}
+
+func Test_runEach(t *testing.T) {
+	content, _ := ini.InsensitiveLoad([]byte(
+		"[dev]\naws_access_key_id=A\naws_secret_access_key=B\n" +
+			"[live]\naws_access_key_id=C\naws_secret_access_key=D\n" +
+			"[role]\nrole_arn=arn:aws:iam::123456789012:role/admin\n",
+	))
+	file := &awsdefault.CredentialsFile{Content: content}
+	var stdout, stderr bytes.Buffer
+	results := runEach(
+		file,
+		[]string{"dev", "live", "role"},
+		[]string{"sh", "-c", `echo $AWS_ACCESS_KEY_ID; test "$AWS_ACCESS_KEY_ID" = A || sleep 5`},
+		2, time.Second, &stdout, &stderr,
+	)
+	var got []string
+	for _, r := range results {
+		got = append(got, r.Profile+" "+r.Status)
+	}
+	if diff := pretty.Compare([]string{"dev ok", "live timeout", "role error"}, got); diff != "" {
+		t.Errorf("runEach() diff: (-want +got)\n%s", diff)
+	}
+	for _, want := range []string{"dev  | A\n", "live | C\n"} {
+		if !strings.Contains(stdout.String(), want) {
+			t.Errorf("runEach() output %q does not contain %q", stdout.String(), want)
+		}
+	}
+
+	var summary bytes.Buffer
+	if writeSummary(&summary, results) {
+		t.Errorf("writeSummary() = true, want false")
+	}
+	if !strings.HasPrefix(summary.String(), "PROFILE") || strings.Count(summary.String(), "\n") != 4 {
+		t.Errorf("writeSummary() = %q", summary.String())
+	}
+}
+
+func Test_eachCommand(t *testing.T) {
+	if got := eachCommand(&awsdefault.CredentialsFile{Path: "somewhere"}); got == nil {
+		t.Errorf("eachCommand() = %v", got)
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+
+	"github.com/peterbueschel/awsdefault"
+	"github.com/urfave/cli"
+)
+
+// signals passed on to the child process
+var forwardedSignals = []os.Signal{os.Interrupt, syscall.SIGTERM, syscall.SIGHUP, syscall.SIGQUIT}
+
+// runWithProfile runs the command with the credentials of the profile and returns its exit
+// code.
+func runWithProfile(p *awsdefault.Profile, args []string) (int, error) {
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Env = p.Environ(os.Environ())
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, forwardedSignals...)
+	defer signal.Stop(sig)
+
+	if err := cmd.Start(); err != nil {
+		return 0, err
+	}
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case s := <-sig:
+				cmd.Process.Signal(s)
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return exitCode(cmd.Wait())
+}
+
+// exitCode returns the exit code of a finished command. A command killed by a signal exits with
+// 128 plus the number of the signal like in a shell; other errors are returned as they are.
+func exitCode(err error) (int, error) {
+	if err == nil {
+		return 0, nil
+	}
+	exit, ok := err.(*exec.ExitError)
+	if !ok {
+		return 0, err
+	}
+	if ws, ok := exit.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+		return 128 + int(ws.Signal()), nil
+	}
+	return exit.ExitCode(), nil
+}
+
+func execCommand(file awsdefault.Switcher) *cli.Command {
+	return &cli.Command{
+		Name:            "exec",
+		Aliases:         []string{"run"},
+		Usage:           "Runs a command with the credentials of the given profile set as AWS_* environment variables.",
+		ArgsUsage:       "<profile> -- <command> [args...]",
+		SkipFlagParsing: true,
+		BashComplete:    completeProfiles(file),
+		Action: func(c *cli.Context) error {
+			args := c.Args()
+			if len(args) > 1 && args[1] == "--" {
+				args = append(cli.Args{args[0]}, args[2:]...)
+			}
+			if len(args) < 2 {
+				return fmt.Errorf("the name of a profile and a command are required")
+			}
+			p, err := awsdefault.ResolveCredentials(file, args[0])
+			if err != nil {
+				return err
+			}
+			code, err := runWithProfile(p, args[1:])
+			if err != nil {
+				return err
+			}
+			if code != 0 {
+				return cli.NewExitError("", code)
+			}
+			return nil
+		},
+	}
+}
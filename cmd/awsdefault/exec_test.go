@@ -0,0 +1,52 @@
+package main
+
+import (
+	"os"
+	"testing"
+
+	"github.com/peterbueschel/awsdefault"
+)
+
+func Test_runWithProfile(t *testing.T) {
+	os.Setenv("AWS_PROFILE", "live")
+	defer os.Unsetenv("AWS_PROFILE")
+	p := &awsdefault.Profile{AccessKeyID: "A", SecretAccessKey: "B"}
+	tests := []struct {
+		name    string
+		args    []string
+		want    int
+		wantErr bool
+	}{
+		{
+			name: "0positiv - credentials are set",
+			args: []string{"sh", "-c", `test "$AWS_ACCESS_KEY_ID" = A && test -z "$AWS_PROFILE"`},
+			want: 0,
+		},
+		{
+			name: "1positiv - exit code is propagated",
+			args: []string{"sh", "-c", "exit 3"},
+			want: 3,
+		},
+		{
+			name: "2positiv - killed by a signal",
+			args: []string{"sh", "-c", "kill -TERM $$"},
+			want: 143,
+		},
+		{
+			name:    "3negativ - missing command",
+			args:    []string{"awsdefault-missing-command"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := runWithProfile(p, tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("runWithProfile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("runWithProfile() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
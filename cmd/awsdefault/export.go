@@ -0,0 +1,148 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/peterbueschel/awsdefault"
+	"github.com/urfave/cli"
+)
+
+// exportFormats writes the environment variables of a profile in the syntax of a shell or tool
+var exportFormats = map[string]func(w io.Writer, p *awsdefault.Profile) error{
+	"sh": func(w io.Writer, p *awsdefault.Profile) error {
+		return writeEnv(w, p, func(k, v string) string {
+			return fmt.Sprintf("export %s='%s'", k, strings.Replace(v, "'", `'\''`, -1))
+		})
+	},
+	"fish": func(w io.Writer, p *awsdefault.Profile) error {
+		r := strings.NewReplacer(`\`, `\\`, "'", `\'`)
+		return writeEnv(w, p, func(k, v string) string {
+			return fmt.Sprintf("set -gx %s '%s';", k, r.Replace(v))
+		})
+	},
+	"powershell": func(w io.Writer, p *awsdefault.Profile) error {
+		return writeEnv(w, p, func(k, v string) string {
+			return fmt.Sprintf("$Env:%s = '%s'", k, strings.Replace(v, "'", "''", -1))
+		})
+	},
+	"cmd": func(w io.Writer, p *awsdefault.Profile) error {
+		// a quote cannot be escaped inside set "..."; a percent sign would expand a variable
+		for _, e := range p.Env() {
+			if strings.ContainsAny(e, "\"\r\n") {
+				return fmt.Errorf("%s contains a quote or a line break, which cmd cannot set", strings.SplitN(e, "=", 2)[0])
+			}
+		}
+		return writeEnv(w, p, func(k, v string) string {
+			return fmt.Sprintf(`set "%s=%s"`, k, strings.Replace(v, "%", "%%", -1))
+		})
+	},
+	"dotenv": func(w io.Writer, p *awsdefault.Profile) error {
+		r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "$", `\$`)
+		return writeEnv(w, p, func(k, v string) string {
+			return fmt.Sprintf(`%s="%s"`, k, r.Replace(v))
+		})
+	},
+	"docker-env": func(w io.Writer, p *awsdefault.Profile) error {
+		// docker --env-file takes the value literally up to the end of the line
+		return writeEnv(w, p, func(k, v string) string { return k + "=" + v })
+	},
+	"json": func(w io.Writer, p *awsdefault.Profile) error {
+		env := make(map[string]string)
+		for _, e := range p.Env() {
+			kv := strings.SplitN(e, "=", 2)
+			env[kv[0]] = kv[1]
+		}
+		return writeJSON(w, env)
+	},
+	"credential-process": func(w io.Writer, p *awsdefault.Profile) error {
+		return writeJSON(w, p.ProcessCredentials())
+	},
+}
+
+// exportFormatNames returns the sorted names of all export formats
+func exportFormatNames() []string {
+	var names []string
+	for n := range exportFormats {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// writeEnv writes one line per environment variable of the profile
+func writeEnv(w io.Writer, p *awsdefault.Profile, line func(k, v string) string) error {
+	for _, e := range p.Env() {
+		kv := strings.SplitN(e, "=", 2)
+		if _, err := fmt.Fprintln(w, line(kv[0], kv[1])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeJSON(w io.Writer, v interface{}) error {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", b)
+	return err
+}
+
+// exportProfile returns the profile with the given name or, if no name is given, the currently
+// used profile
+func exportProfile(file awsdefault.Switcher, name string) (*awsdefault.Profile, error) {
+	if len(name) > 0 {
+		return file.GetProfileBy(name)
+	}
+	_, p, err := awsdefault.GetActiveProfile(file)
+	return p, err
+}
+
+func printCredential(file awsdefault.Switcher) *cli.Command {
+	return &cli.Command{
+		Name:      "export",
+		Aliases:   []string{"envs"},
+		Usage:     "Returns the credentials and the region of the currently used or a given profile in form of export commands.",
+		ArgsUsage: "[profile]",
+		BashComplete: func(c *cli.Context) {
+			switch previousArg(os.Args) {
+			case "-f", "--format":
+				writeLines(c.App.Writer, exportFormatNames())
+			default:
+				completeProfiles(file)(c)
+			}
+		},
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "format, f",
+				Value: "sh",
+				Usage: "one of " + strings.Join(exportFormatNames(), ", "),
+			},
+			cli.BoolFlag{
+				Name:  "reveal",
+				Usage: "print the secrets in plain text also into a terminal",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			write, ok := exportFormats[c.String("format")]
+			if !ok {
+				return fmt.Errorf("unknown export format %q", c.String("format"))
+			}
+			p, err := exportProfile(file, c.Args().First())
+			if err != nil {
+				return err
+			}
+			// the output is meant for eval or files; a terminal only shows the masked secrets
+			if isTerminal(os.Stdout) && !c.Bool("reveal") {
+				p = maskProfile(p)
+			}
+			return write(os.Stdout, p)
+		},
+	}
+}
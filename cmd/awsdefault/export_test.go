@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/go-ini/ini"
+	"github.com/kylelemons/godebug/pretty"
+	"github.com/peterbueschel/awsdefault"
+)
+
+func Test_exportFormats(t *testing.T) {
+	p := &awsdefault.Profile{
+		AccessKeyID:     "A",
+		SecretAccessKey: `it's"$\`,
+		Region:          "eu-west-1",
+	}
+	percent := &awsdefault.Profile{AccessKeyID: "A", SecretAccessKey: "50%off^&<>|"}
+	tests := []struct {
+		format  string
+		profile *awsdefault.Profile // p, if nil
+		want    string
+		wantErr bool
+	}{
+		{
+			format: "sh",
+			want:   "export AWS_ACCESS_KEY_ID='A'\nexport AWS_SECRET_ACCESS_KEY='it'\\''s\"$\\'\nexport AWS_REGION='eu-west-1'\nexport AWS_DEFAULT_REGION='eu-west-1'\n",
+		},
+		{
+			format: "fish",
+			want:   "set -gx AWS_ACCESS_KEY_ID 'A';\nset -gx AWS_SECRET_ACCESS_KEY 'it\\'s\"$\\\\';\nset -gx AWS_REGION 'eu-west-1';\nset -gx AWS_DEFAULT_REGION 'eu-west-1';\n",
+		},
+		{
+			format: "powershell",
+			want:   "$Env:AWS_ACCESS_KEY_ID = 'A'\n$Env:AWS_SECRET_ACCESS_KEY = 'it''s\"$\\'\n$Env:AWS_REGION = 'eu-west-1'\n$Env:AWS_DEFAULT_REGION = 'eu-west-1'\n",
+		},
+		{
+			format:  "cmd",
+			wantErr: true, // the quote cannot be escaped
+		},
+		{
+			format:  "cmd",
+			profile: percent,
+			want:    "set \"AWS_ACCESS_KEY_ID=A\"\nset \"AWS_SECRET_ACCESS_KEY=50%%off^&<>|\"\n",
+		},
+		{
+			format: "dotenv",
+			want:   "AWS_ACCESS_KEY_ID=\"A\"\nAWS_SECRET_ACCESS_KEY=\"it's\\\"\\$\\\\\"\nAWS_REGION=\"eu-west-1\"\nAWS_DEFAULT_REGION=\"eu-west-1\"\n",
+		},
+		{
+			format: "docker-env",
+			want:   "AWS_ACCESS_KEY_ID=A\nAWS_SECRET_ACCESS_KEY=it's\"$\\\nAWS_REGION=eu-west-1\nAWS_DEFAULT_REGION=eu-west-1\n",
+		},
+		{
+			format: "json",
+			want:   "{\n  \"AWS_ACCESS_KEY_ID\": \"A\",\n  \"AWS_DEFAULT_REGION\": \"eu-west-1\",\n  \"AWS_REGION\": \"eu-west-1\",\n  \"AWS_SECRET_ACCESS_KEY\": \"it's\\\"$\\\\\"\n}\n",
+		},
+		{
+			format: "credential-process",
+			want:   "{\n  \"Version\": 1,\n  \"AccessKeyId\": \"A\",\n  \"SecretAccessKey\": \"it's\\\"$\\\\\"\n}\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			profile := tt.profile
+			if profile == nil {
+				profile = p
+			}
+			var buf bytes.Buffer
+			if err := exportFormats[tt.format](&buf, profile); (err != nil) != tt.wantErr {
+				t.Errorf("exportFormats[%s]() error = %v, wantErr %v", tt.format, err, tt.wantErr)
+				return
+			}
+			if diff := pretty.Compare(tt.want, buf.String()); diff != "" {
+				t.Errorf("exportFormats[%s]() diff: (-want +got)\n%s", tt.format, diff)
+			}
+		})
+	}
+}
+
+func Test_exportProfile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		profile string
+		wantID  string
+		wantErr bool
+	}{
+		{
+			name:    "positive — default profile",
+			content: "[default]\naws_access_key_id=A\naws_secret_access_key=B\n[dev]\naws_access_key_id=C\naws_secret_access_key=D",
+			wantID:  "A",
+		},
+		{
+			name:    "positive — named profile",
+			content: "[default]\naws_access_key_id=A\naws_secret_access_key=B\n[dev]\naws_access_key_id=C\naws_secret_access_key=D",
+			profile: "dev",
+			wantID:  "C",
+		},
+		{
+			name:    "negative — no default",
+			content: "[dev]\naws_access_key_id=C\naws_secret_access_key=D",
+			wantErr: true,
+		},
+		{
+			name:    "negative — unknown profile",
+			content: "[dev]\naws_access_key_id=C\naws_secret_access_key=D",
+			profile: "xxxxxxx",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content, _ := ini.InsensitiveLoad([]byte(tt.content))
+			p, err := exportProfile(&awsdefault.CredentialsFile{Content: content}, tt.profile)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("exportProfile() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err == nil && p.AccessKeyID != tt.wantID {
+				t.Errorf("exportProfile() got = %v, want %v", p.AccessKeyID, tt.wantID)
+			}
+		})
+	}
+}
+
+func Test_printCredential(t *testing.T) {
+	if got := printCredential(&awsdefault.CredentialsFile{Path: "somewhere"}); got == nil {
+		t.Errorf("printCredential() = %v", got)
+	}
+}
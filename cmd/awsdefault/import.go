@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/peterbueschel/awsdefault"
+	"github.com/urfave/cli"
+)
+
+var importFlags = []cli.Flag{
+	cli.StringFlag{
+		Name:  "name, n",
+		Usage: "name of the new profile",
+	},
+	cli.BoolFlag{
+		Name:  "force, y",
+		Usage: "overwrite an existing profile and import duplicated keys without asking",
+	},
+}
+
+// confirm asks the question and reports whether it was answered with yes. Pass the same
+// bufio.Reader for several questions, otherwise buffered answers get lost.
+func confirm(in io.Reader, out io.Writer, question string) bool {
+	fmt.Fprintf(out, "%s [y/N] ", question)
+	answer, _ := bufio.NewReader(in).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true
+	}
+	return false
+}
+
+// importProfile validates the keys and stores them under the name. Duplicated keys and an
+// existing profile require a confirmation, unless force is set.
+func importProfile(file *awsdefault.CredentialsFile, name string, p *awsdefault.Profile, force bool, in io.Reader, out io.Writer) error {
+	if len(name) == 0 {
+		return fmt.Errorf("the name of the new profile is required (--name)")
+	}
+	if err := awsdefault.ValidateKeys(p); err != nil {
+		return err
+	}
+	in = bufio.NewReader(in)
+	var dups []string
+	for _, n := range file.ProfilesWithKeyID(p.AccessKeyID) {
+		if n != name {
+			dups = append(dups, n)
+		}
+	}
+	if len(dups) > 0 && !force {
+		q := fmt.Sprintf("the AWS_ACCESS_KEY_ID is already stored in %s; import it anyway?", strings.Join(dups, ", "))
+		if !confirm(in, out, q) {
+			return fmt.Errorf("import aborted")
+		}
+	}
+	err := file.AddProfile(name, p, force)
+	if err == awsdefault.ErrProfileExists {
+		if !confirm(in, out, fmt.Sprintf("the profile %s already exists; overwrite it?", name)) {
+			return fmt.Errorf("import aborted")
+		}
+		err = file.AddProfile(name, p, true)
+	}
+	if err == nil {
+		fmt.Fprintf(out, "imported the profile %s\n", name)
+	}
+	return err
+}
+
+func importCSV(file *awsdefault.CredentialsFile) cli.Command {
+	return cli.Command{
+		Name:      "csv",
+		Usage:     "Imports the credentials.csv downloaded from the IAM console.",
+		ArgsUsage: "<file>",
+		Flags:     importFlags,
+		Action: func(c *cli.Context) error {
+			if c.NArg() < 1 {
+				return fmt.Errorf("the path of the CSV file is required")
+			}
+			f, err := os.Open(c.Args().First())
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			profiles, err := awsdefault.ParseCredentialsCSV(f)
+			if err != nil {
+				return err
+			}
+			if len(profiles) > 1 {
+				return fmt.Errorf("the CSV file contains %d keys; only one key per file can be imported", len(profiles))
+			}
+			return importProfile(file, c.String("name"), profiles[0], c.Bool("force"), os.Stdin, os.Stderr)
+		},
+	}
+}
+
+func importEnv(file *awsdefault.CredentialsFile) cli.Command {
+	return cli.Command{
+		Name:  "env",
+		Usage: "Imports the credentials from the AWS_* environment variables.",
+		Flags: importFlags,
+		Action: func(c *cli.Context) error {
+			p, err := awsdefault.ProfileFromEnv(os.Environ())
+			if err != nil {
+				return err
+			}
+			return importProfile(file, c.String("name"), p, c.Bool("force"), os.Stdin, os.Stderr)
+		},
+	}
+}
+
+func importProfiles(file *awsdefault.CredentialsFile) *cli.Command {
+	return &cli.Command{
+		Name:  "import",
+		Usage: "Creates a new profile from a credentials.csv of the IAM console or from environment variables.",
+		Subcommands: []cli.Command{
+			importCSV(file),
+			importEnv(file),
+		},
+	}
+}
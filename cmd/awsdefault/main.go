@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"io"
 	"log"
 	"os"
 
@@ -9,42 +10,58 @@ import (
 	"github.com/urfave/cli"
 )
 
-func getProfiles(file *awsdefault.CredentialsFile) *cli.Command {
+func getProfiles(file awsdefault.Switcher) *cli.Command {
 	return &cli.Command{
 		Name:    "list",
 		Aliases: []string{"ls", "profiles", "available"},
 		Usage:   "Returns all available profiles from the AWS credentials file.",
 		Action: func(c *cli.Context) error {
-			names := file.GetProfilesNames()
-			for _, n := range names {
-				fmt.Println(n)
+			out, err := newPrinter(c)
+			if err != nil {
+				return err
+			}
+			infos, err := awsdefault.GetProfilesInfo(file)
+			if err != nil {
+				return err
 			}
-			return nil
+			return out.print(infos, func(w io.Writer) {
+				for _, i := range infos {
+					fmt.Fprintln(w, i.Name)
+				}
+			})
 		},
 	}
 }
 
-func getUsedProfile(file *awsdefault.CredentialsFile) *cli.Command {
+func getUsedProfile(file awsdefault.Switcher) *cli.Command {
 	return &cli.Command{
 		Name:    "get",
 		Aliases: []string{"show", "is", "now", "curr"},
 		Usage:   "Returns the current AWS default profile.",
 		Action: func(c *cli.Context) error {
-			n, idx, err := file.GetUsedProfileNameAndIndex()
+			out, err := newPrinter(c)
 			if err != nil {
-				if idx == -2 {
-					fmt.Println(n)
-					return nil
-				}
 				return err
 			}
-			fmt.Println(n)
-			return nil
+			n, idx, err := file.GetUsedProfileNameAndIndex()
+			if err != nil && idx != -2 {
+				return err
+			}
+			var info *awsdefault.ProfileInfo // stays nil, if no default is set
+			if idx >= 0 {
+				p, err := file.GetProfileBy(n)
+				if err != nil {
+					return err
+				}
+				i := p.Info(n, true)
+				info = &i
+			}
+			return out.print(info, func(w io.Writer) { fmt.Fprintln(w, n) })
 		},
 	}
 }
 
-func unsetDefaultProfile(file *awsdefault.CredentialsFile) *cli.Command {
+func unsetDefaultProfile(file awsdefault.Switcher) *cli.Command {
 	return &cli.Command{
 		Name:    "unset",
 		Aliases: []string{"rm", "stop", "not"},
@@ -55,11 +72,12 @@ func unsetDefaultProfile(file *awsdefault.CredentialsFile) *cli.Command {
 	}
 }
 
-func setDefaultProfile(file *awsdefault.CredentialsFile) *cli.Command {
+func setDefaultProfile(file awsdefault.Switcher) *cli.Command {
 	return &cli.Command{
-		Name:    "set",
-		Aliases: []string{"to", "should", "replace"},
-		Usage:   "Set/replace the AWS default profile to a given profile. Requires a profile name.",
+		Name:         "set",
+		Aliases:      []string{"to", "should", "replace"},
+		Usage:        "Set/replace the AWS default profile to a given profile. Requires a profile name; 'profile@region' also switches the region.",
+		BashComplete: completeProfiles(file),
 		Action: func(c *cli.Context) error {
 			if c.NArg() < 1 {
 				return fmt.Errorf(
@@ -71,76 +89,112 @@ func setDefaultProfile(file *awsdefault.CredentialsFile) *cli.Command {
 	}
 }
 
-func getUsedID(file *awsdefault.CredentialsFile) *cli.Command {
+func getUsedID(file awsdefault.Switcher) *cli.Command {
 	return &cli.Command{
 		Name:    "id",
 		Aliases: []string{"aws_access_key_id"},
 		Usage:   "Returns the AWS_ACCESS_KEY_ID of the currently used profile",
 		Action: func(c *cli.Context) error {
-			id, err := file.GetUsedID()
+			out, err := newPrinter(c)
 			if err != nil {
 				return err
 			}
-			fmt.Println(id)
-			return nil
+			_, p, err := awsdefault.GetActiveProfile(file)
+			if err != nil {
+				return err
+			}
+			id := p.AccessKeyID
+			if len(id) == 0 {
+				return fmt.Errorf("AWS_ACCESS_KEY_ID is not set inside the used profile")
+			}
+			return out.print(
+				map[string]string{"aws_access_key_id": id},
+				func(w io.Writer) { fmt.Fprintln(w, id) },
+			)
 		},
 	}
 }
 
-func getUsedKey(file *awsdefault.CredentialsFile) *cli.Command {
+func getUsedKey(file awsdefault.Switcher) *cli.Command {
 	return &cli.Command{
 		Name:    "key",
 		Aliases: []string{"aws_secret_access_key"},
-		Usage:   "Returns the AWS_SECRET_ACCESS_KEY of the currently used profile",
+		Usage:   "Returns the masked AWS_SECRET_ACCESS_KEY of the currently used profile",
+		Flags:   revealFlags,
 		Action: func(c *cli.Context) error {
-			k, err := file.GetUsedKey()
+			out, err := newPrinter(c)
 			if err != nil {
 				return err
 			}
-			fmt.Println(k)
-			return nil
-		},
-	}
-}
-
-func printCredential(file *awsdefault.CredentialsFile) *cli.Command {
-	return &cli.Command{
-		Name:    "export",
-		Aliases: []string{"envs"},
-		Usage:   "Returns the AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY of the currently used profile in form of export commands.",
-		Action: func(c *cli.Context) error {
-			id, err := file.GetUsedID()
+			reveal, err := revealSecrets(c, os.Stdout)
 			if err != nil {
 				return err
 			}
-			k, err := file.GetUsedKey()
+			_, p, err := awsdefault.GetActiveProfile(file)
 			if err != nil {
 				return err
 			}
-			fmt.Printf("export AWS_ACCESS_KEY_ID=%s\nexport AWS_SECRET_ACCESS_KEY=%s\n", id, k)
-			return nil
+			if len(p.SecretAccessKey) == 0 {
+				return fmt.Errorf("AWS_SECRET_ACCESS_KEY is not set inside the used profile")
+			}
+			k := p.SecretAccessKey.String()
+			if reveal {
+				k = p.SecretAccessKey.Reveal()
+			}
+			return out.print(
+				map[string]string{"aws_secret_access_key": k},
+				func(w io.Writer) { fmt.Fprintln(w, k) },
+			)
 		},
 	}
 }
 
+// newApp returns the command line tool working on the credentials file; the switcher lists and
+// switches the profiles
+func newApp(file *awsdefault.CredentialsFile, switcher awsdefault.Switcher) *cli.App {
+	app := cli.NewApp()
+	app.Flags = outputFlags
+	app.EnableBashCompletion = true
+
+	app.Commands = []cli.Command{
+		*setDefaultProfile(switcher),
+		*unsetDefaultProfile(switcher),
+		*nextProfile(switcher),
+		*prevProfile(switcher),
+		*getUsedProfile(switcher),
+		*statusCommand(file, switcher),
+		*getUsedID(switcher),
+		*getUsedKey(switcher),
+		*printCredential(switcher),
+		*getProfiles(switcher),
+		*execCommand(switcher),
+		*eachCommand(switcher),
+		*tagProfile(switcher),
+		*regionCommand(switcher),
+		*credentialProcess(switcher),
+		*switchMode(file),
+		*importProfiles(file),
+		*backupProfiles(),
+		*restoreProfiles(),
+		*doctor(file),
+		*fixPermissions(),
+		*serveContainerCredentials(),
+		*serveInstanceMetadata(),
+		*completion(),
+	}
+	return app
+}
+
 func main() {
-	file, err := awsdefault.GetCredentialsFile()
+	// a missing credentials file is created by the first imported or restored profile
+	file, err := awsdefault.OpenCredentialsFile()
 	if err != nil {
 		log.Fatalf("[AWSDEFAULT][ERROR] %v.\n", err)
 	}
 
-	app := cli.NewApp()
-
-	app.Commands = []cli.Command{
-		*setDefaultProfile(file),
-		*unsetDefaultProfile(file),
-		*getUsedProfile(file),
-		*getUsedID(file),
-		*getUsedKey(file),
-		*printCredential(file),
-		*getProfiles(file),
-	}
-	err = app.Run(os.Args)
+	// a running awsdefaultd takes over listing and switching of the profiles; in the
+	// credential_process mode switching only records the chosen profile
+	err = newApp(file, awsdefault.NewSwitcher(file)).Run(os.Args)
 	if err != nil {
 		log.Fatalf("[AWSDEFAULT][ERROR] %s.\n", err)
 	}
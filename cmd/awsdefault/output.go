@@ -0,0 +1,236 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/peterbueschel/awsdefault"
+	"github.com/urfave/cli"
+)
+
+// supported values of the global --output flag
+const (
+	outputText = "text"
+	outputJSON = "json"
+	outputYAML = "yaml"
+	outputTSV  = "tsv"
+)
+
+var outputFlags = []cli.Flag{
+	cli.StringFlag{
+		Name:  "output, o",
+		Value: outputText,
+		Usage: "format of the output of read commands: text, json, yaml or tsv",
+	},
+	cli.StringFlag{
+		Name:  "format, f",
+		Usage: "Go template applied to the output of read commands, e.g. '{{.name}}'",
+	},
+}
+
+// printer writes the result of a read command in the format selected via the global flags
+type printer struct {
+	output string
+	format *template.Template
+	w      io.Writer
+}
+
+func newPrinter(c *cli.Context) (*printer, error) {
+	p := &printer{output: c.GlobalString("output"), w: os.Stdout}
+	switch p.output {
+	case outputText, outputJSON, outputYAML, outputTSV:
+	default:
+		return nil, fmt.Errorf("unknown output format %q", p.output)
+	}
+	if f := c.GlobalString("format"); len(f) > 0 {
+		t, err := template.New("format").Parse(f)
+		if err != nil {
+			return nil, err
+		}
+		p.format = t
+	}
+	return p, nil
+}
+
+// print writes v, which is either a single value or a slice of values. The text function is
+// used for the plain text output.
+func (p *printer) print(v interface{}, text func(w io.Writer)) error {
+	if p.format != nil {
+		return p.template(v)
+	}
+	switch p.output {
+	case outputJSON:
+		b, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(p.w, "%s\n", b)
+		return err
+	case outputYAML:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		return toYAML(p.w, b)
+	case outputTSV:
+		return p.tsv(v)
+	}
+	text(p.w)
+	return nil
+}
+
+// template executes the Go template for each element of the result; the elements are passed
+// as generic JSON objects, hence the template uses the same keys as the JSON output
+func (p *printer) template(v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	var data interface{}
+	if err = json.Unmarshal(b, &data); err != nil {
+		return err
+	}
+	items, ok := data.([]interface{})
+	if !ok {
+		if data == nil {
+			return nil
+		}
+		items = []interface{}{data}
+	}
+	for _, i := range items {
+		if err = p.format.Execute(p.w, i); err != nil {
+			return err
+		}
+		fmt.Fprintln(p.w)
+	}
+	return nil
+}
+
+// tsv writes one line per element with the fields separated by tabs
+func (p *printer) tsv(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice {
+		rv = reflect.ValueOf([]interface{}{v})
+	}
+	for i := 0; i < rv.Len(); i++ {
+		var fields []string
+		switch e := rv.Index(i).Interface().(type) {
+		case awsdefault.ProfileInfo:
+			expiry := ""
+			if e.Expiry != nil {
+				expiry = e.Expiry.Format(time.RFC3339)
+			}
+			fields = []string{e.Name, strconv.FormatBool(e.Active), e.Type, e.Region, e.KeyID, expiry}
+		case *awsdefault.ProfileInfo:
+			if e == nil {
+				continue
+			}
+			return p.tsv(*e)
+		case map[string]string:
+			for _, k := range sortedKeys(e) {
+				fields = append(fields, e[k])
+			}
+		default:
+			fields = []string{fmt.Sprint(e)}
+		}
+		if _, err := fmt.Fprintln(p.w, strings.Join(fields, "\t")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// toYAML converts a JSON document into YAML. JSON strings are valid double-quoted YAML scalars,
+// which keeps the output of both formats consistent.
+func toYAML(w io.Writer, b []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	writeYAML(&buf, v, 0, false)
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func writeYAML(buf *bytes.Buffer, v interface{}, indent int, inList bool) {
+	pad := strings.Repeat("  ", indent)
+	switch e := v.(type) {
+	case map[string]interface{}:
+		if len(e) == 0 {
+			buf.WriteString("{}\n")
+			return
+		}
+		for i, k := range sortedKeys(e) {
+			if i > 0 || !inList {
+				buf.WriteString(pad)
+			}
+			buf.WriteString(k + ":")
+			writeYAMLValue(buf, e[k], indent+1)
+		}
+	case []interface{}:
+		if len(e) == 0 {
+			buf.WriteString("[]\n")
+			return
+		}
+		for _, i := range e {
+			buf.WriteString(pad + "- ")
+			writeYAML(buf, i, indent+1, true)
+		}
+	default:
+		writeYAMLScalar(buf, e)
+	}
+}
+
+func writeYAMLValue(buf *bytes.Buffer, v interface{}, indent int) {
+	switch e := v.(type) {
+	case map[string]interface{}:
+		if len(e) > 0 {
+			buf.WriteString("\n")
+			writeYAML(buf, e, indent, false)
+			return
+		}
+	case []interface{}:
+		if len(e) > 0 {
+			buf.WriteString("\n")
+			writeYAML(buf, e, indent, false)
+			return
+		}
+	}
+	buf.WriteString(" ")
+	writeYAML(buf, v, indent, false)
+}
+
+func writeYAMLScalar(buf *bytes.Buffer, v interface{}) {
+	switch e := v.(type) {
+	case nil:
+		buf.WriteString("null")
+	case string:
+		b, _ := json.Marshal(e)
+		buf.Write(b)
+	default:
+		buf.WriteString(fmt.Sprint(e))
+	}
+	buf.WriteString("\n")
+}
+
+// sortedKeys returns the keys of a map in alphabetical order
+func sortedKeys(m interface{}) []string {
+	var keys []string
+	for _, k := range reflect.ValueOf(m).MapKeys() {
+		keys = append(keys, k.String())
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/kylelemons/godebug/pretty"
+	"github.com/peterbueschel/awsdefault"
+)
+
+func Test_printer_print(t *testing.T) {
+	expiry := time.Date(2019, 3, 1, 12, 0, 0, 0, time.UTC)
+	infos := []awsdefault.ProfileInfo{
+		{Name: "dev", Active: true, Type: awsdefault.TypeStatic, KeyID: "AKIA************MPLE"},
+		{Name: "live", Type: awsdefault.TypeSession, Region: "eu-central-1", Expiry: &expiry},
+	}
+	var noDefault *awsdefault.ProfileInfo
+	tests := []struct {
+		name   string
+		output string
+		format string
+		value  interface{}
+		want   string
+	}{
+		{
+			name:   "positive — text",
+			output: outputText,
+			value:  infos,
+			want:   "text\n",
+		},
+		{
+			name:   "positive — json",
+			output: outputJSON,
+			value:  infos[:1],
+			want: `[
+  {
+    "name": "dev",
+    "active": true,
+    "type": "static",
+    "key_id": "AKIA************MPLE"
+  }
+]
+`,
+		},
+		{
+			name:   "positive — json without default",
+			output: outputJSON,
+			value:  noDefault,
+			want:   "null\n",
+		},
+		{
+			name:   "positive — yaml",
+			output: outputYAML,
+			value:  infos,
+			want: `- active: true
+  key_id: "AKIA************MPLE"
+  name: "dev"
+  type: "static"
+- active: false
+  expiry: "2019-03-01T12:00:00Z"
+  name: "live"
+  region: "eu-central-1"
+  type: "session"
+`,
+		},
+		{
+			name:   "positive — yaml of an empty list",
+			output: outputYAML,
+			value:  []awsdefault.ProfileInfo{},
+			want:   "[]\n",
+		},
+		{
+			name:   "positive — tsv",
+			output: outputTSV,
+			value:  infos,
+			want:   "dev\ttrue\tstatic\t\tAKIA************MPLE\t\nlive\tfalse\tsession\teu-central-1\t\t2019-03-01T12:00:00Z\n",
+		},
+		{
+			name:   "positive — tsv of a single value",
+			output: outputTSV,
+			value:  map[string]string{"aws_access_key_id": "A"},
+			want:   "A\n",
+		},
+		{
+			name:   "positive — template for each element",
+			output: outputText,
+			format: "{{.name}}={{.active}}",
+			value:  infos,
+			want:   "dev=true\nlive=false\n",
+		},
+		{
+			name:   "positive — template without default",
+			output: outputText,
+			format: "{{.name}}",
+			value:  noDefault,
+			want:   "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			p := &printer{output: tt.output, w: &buf}
+			if len(tt.format) > 0 {
+				p.format = template.Must(template.New("format").Parse(tt.format))
+			}
+			err := p.print(tt.value, func(w io.Writer) { io.WriteString(w, "text\n") })
+			if err != nil {
+				t.Errorf("printer.print() error = %v", err)
+				return
+			}
+			if diff := pretty.Compare(tt.want, buf.String()); diff != "" {
+				t.Errorf("printer.print() diff: (-want +got)\n%s", diff)
+			}
+		})
+	}
+}
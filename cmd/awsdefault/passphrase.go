@@ -0,0 +1,47 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// readPassphrase returns the passphrase given via AWSDEFAULT_PASSPHRASE or asks for it without
+// echoing the input; with repeat set it has to be entered twice.
+func readPassphrase(repeat bool) (string, error) {
+	if p := os.Getenv("AWSDEFAULT_PASSPHRASE"); len(p) > 0 {
+		return p, nil
+	}
+	in := bufio.NewReader(os.Stdin)
+	p, err := askPassphrase(in, "passphrase: ")
+	if err != nil {
+		return "", err
+	}
+	if len(p) == 0 {
+		return "", fmt.Errorf("the passphrase must not be empty")
+	}
+	if repeat {
+		again, err := askPassphrase(in, "repeat the passphrase: ")
+		if err != nil {
+			return "", err
+		}
+		if p != again {
+			return "", fmt.Errorf("the passphrases do not match")
+		}
+	}
+	return p, nil
+}
+
+func askPassphrase(in *bufio.Reader, prompt string) (string, error) {
+	fmt.Fprint(os.Stderr, prompt)
+	restore := disableEcho(os.Stdin)
+	line, err := in.ReadString('\n')
+	restore()
+	fmt.Fprintln(os.Stderr)
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
@@ -0,0 +1,19 @@
+//go:build !linux
+// +build !linux
+
+package main
+
+import "os"
+
+// disableEcho is only supported on Linux; elsewhere the passphrase stays visible, use the
+// environment variable AWSDEFAULT_PASSPHRASE instead
+func disableEcho(f *os.File) func() {
+	return func() {}
+}
+
+// isTerminal tells whether f is a character device, which is the best guess for a terminal
+// without the termios of Linux
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
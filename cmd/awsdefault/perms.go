@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/peterbueschel/awsdefault"
+	"github.com/urfave/cli"
+)
+
+func fixPermissions() *cli.Command {
+	return &cli.Command{
+		Name:  "fix-perms",
+		Usage: "Restricts the access to the credentials, the AWS config, the awsdefault settings and ~/.aws to their owner; other directories are only reported.",
+		Action: func(c *cli.Context) error {
+			out, err := newPrinter(c)
+			if err != nil {
+				return err
+			}
+			changes, err := awsdefault.FixPermissions()
+			if err != nil {
+				return err
+			}
+			if changes == nil {
+				changes = []awsdefault.PermissionChange{}
+			}
+			return out.print(changes, func(w io.Writer) {
+				for _, ch := range changes {
+					if ch.Skipped {
+						fmt.Fprintf(w, "%s: %04o kept; restrict it via 'chmod %04o %s'\n", ch.Path, ch.Old, ch.New, ch.Path)
+						continue
+					}
+					fmt.Fprintf(w, "%s: %04o -> %04o\n", ch.Path, ch.Old, ch.New)
+				}
+			})
+		},
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/peterbueschel/awsdefault"
+	"github.com/urfave/cli"
+)
+
+func regionCommand(file awsdefault.Switcher) *cli.Command {
+	return &cli.Command{
+		Name:      "region",
+		Usage:     "Shows or sets the region of the default profile without switching the profile.",
+		ArgsUsage: "[region]",
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "profile, p",
+				Usage: "show or set the preferred region of this profile, used whenever it becomes the default",
+			},
+			cli.BoolFlag{
+				Name:  "clear",
+				Usage: "remove the preferred region of the profile",
+			},
+			cli.BoolFlag{
+				Name:  "list, l",
+				Usage: "list all known regions",
+			},
+		},
+		BashComplete: func(c *cli.Context) {
+			if c.NArg() == 0 {
+				writeLines(c.App.Writer, awsdefault.RegionNames())
+			}
+		},
+		Action: func(c *cli.Context) error {
+			out, err := newPrinter(c)
+			if err != nil {
+				return err
+			}
+			region := c.Args().First()
+			switch {
+			case c.Bool("list"):
+				return out.print(awsdefault.Regions, func(w io.Writer) {
+					t := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+					for _, r := range awsdefault.Regions {
+						fmt.Fprintf(t, "%s\t%s\t%s\n", r.Name, r.Partition, r.Description)
+					}
+					t.Flush()
+				})
+			case len(c.String("profile")) > 0:
+				return preferredRegion(c, out, file, c.String("profile"), region)
+			case len(region) > 0:
+				if _, err = awsdefault.GetRegion(region); err != nil {
+					return err
+				}
+				n, idx, err := file.GetUsedProfileNameAndIndex()
+				if idx < 0 {
+					if err == nil {
+						err = fmt.Errorf("no profile is set as default")
+					}
+					return err
+				}
+				return file.SetDefaultTo(n + "@" + region)
+			}
+			_, p, err := awsdefault.GetActiveProfile(file)
+			if err != nil {
+				return err
+			}
+			if len(p.Region) == 0 {
+				return fmt.Errorf("no region is set for the default profile")
+			}
+			return out.print(
+				map[string]string{"region": p.Region},
+				func(w io.Writer) { fmt.Fprintln(w, p.Region) },
+			)
+		},
+	}
+}
+
+// preferredRegion shows, sets or removes the preferred region of a profile
+func preferredRegion(c *cli.Context, out *printer, file awsdefault.Switcher, name, region string) error {
+	if _, err := file.GetProfileBy(name); err != nil {
+		return err
+	}
+	s, err := awsdefault.GetSettings()
+	if err != nil {
+		return err
+	}
+	switch {
+	case c.Bool("clear"):
+		return s.SetPreferredRegion(name, "")
+	case len(region) > 0:
+		return s.SetPreferredRegion(name, region)
+	}
+	r := s.PreferredRegion(name)
+	return out.print(
+		map[string]string{"region": r},
+		func(w io.Writer) { fmt.Fprintln(w, r) },
+	)
+}
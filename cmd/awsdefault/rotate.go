@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/peterbueschel/awsdefault"
+	"github.com/urfave/cli"
+)
+
+var rotateFlags = []cli.Flag{
+	cli.BoolFlag{
+		Name:  "none, n",
+		Usage: "include the state without default profile in the rotation (rotation_none of the settings)",
+	},
+	cli.BoolFlag{
+		Name:  "yes, y",
+		Usage: "switch to protected (production) profiles without asking",
+	},
+}
+
+func nextProfile(file awsdefault.Switcher) *cli.Command {
+	return rotateCommand(file, "next", "Switches to the next profile of the rotation.", false)
+}
+
+func prevProfile(file awsdefault.Switcher) *cli.Command {
+	return rotateCommand(file, "prev", "Switches to the previous profile of the rotation.", true)
+}
+
+func rotateCommand(file awsdefault.Switcher, name, usage string, backward bool) *cli.Command {
+	return &cli.Command{
+		Name:  name,
+		Usage: usage + " The rotation is configured in the settings, otherwise all profiles; protected profiles are skipped unless confirmed.",
+		Flags: rotateFlags,
+		Action: func(c *cli.Context) error {
+			settings := settingsOrNil()
+			none := c.Bool("none") || settings != nil && settings.RotationWithNone()
+			var accept func(string) bool
+			if !c.Bool("yes") {
+				accept = confirmProtected(settings, os.Stdin, os.Stderr)
+			}
+			return rotate(file, awsdefault.RotationOf(file, settings, none), backward, accept)
+		},
+	}
+}
+
+// rotate switches from the current default profile to its neighbour inside the rotation; the
+// empty name unsets the default profile
+func rotate(file awsdefault.Switcher, rotation []string, backward bool, accept func(string) bool) error {
+	curr, idx, _ := file.GetUsedProfileNameAndIndex()
+	if idx < 0 {
+		curr = "" // no default or a default matching no profile
+	}
+	next, err := awsdefault.Cycle(rotation, curr, backward, accept)
+	if err != nil {
+		return err
+	}
+	if len(next) == 0 {
+		return file.UnSetDefault()
+	}
+	return file.SetDefaultTo(next)
+}
+
+// confirmProtected returns the function accepting all profiles, which are not tagged as
+// production profiles; those need a confirmation on a terminal and are skipped otherwise, e.g.
+// when scrolling over a status bar.
+func confirmProtected(settings *awsdefault.Settings, in *os.File, out io.Writer) func(string) bool {
+	interactive := isTerminal(in)
+	r := bufio.NewReader(in)
+	return func(name string) bool {
+		if settings == nil || !settings.IsProduction(name) {
+			return true
+		}
+		return interactive && confirm(r, out, fmt.Sprintf("%s is a production profile; switch to it?", name))
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-ini/ini"
+	"github.com/peterbueschel/awsdefault"
+)
+
+func Test_rotate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "awsdefault")
+	if err != nil {
+		t.Fatalf("could not create temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	os.Setenv("AWS_CONFIG_FILE", filepath.Join(dir, "config"))
+	os.Setenv("AWSDEFAULT_CONFIG_FILE", filepath.Join(dir, "awsdefault"))
+	defer os.Unsetenv("AWS_CONFIG_FILE")
+	defer os.Unsetenv("AWSDEFAULT_CONFIG_FILE")
+	credentials := []byte(
+		"[dev]\naws_access_key_id=A\naws_secret_access_key=B\n" +
+			"[live]\naws_access_key_id=C\naws_secret_access_key=D\n" +
+			"[test]\naws_access_key_id=E\naws_secret_access_key=F\n",
+	)
+	path := filepath.Join(dir, "credentials")
+	if err = ioutil.WriteFile(path, credentials, 0600); err != nil {
+		t.Fatalf("could not write credentials file: %s", err)
+	}
+	content, _ := ini.InsensitiveLoad(credentials)
+	file := &awsdefault.CredentialsFile{Content: content, Path: path}
+	settings, err := awsdefault.GetSettings()
+	if err != nil {
+		t.Fatalf("could not load settings: %s", err)
+	}
+	if err = settings.SetTags("live", []string{"prod"}); err != nil {
+		t.Fatalf("could not tag live: %s", err)
+	}
+	// not a terminal, hence protected profiles are skipped
+	in, err := os.Create(filepath.Join(dir, "stdin"))
+	if err != nil {
+		t.Fatalf("could not create stdin: %s", err)
+	}
+	defer in.Close()
+	accept := confirmProtected(settings, in, ioutil.Discard)
+	rotation := []string{"dev", "live", "test", ""}
+
+	for _, want := range []string{"dev", "test", "", "dev"} {
+		if err = rotate(file, rotation, false, accept); err != nil {
+			t.Fatalf("rotate() error = %v", err)
+		}
+		if got, idx, _ := file.GetUsedProfileNameAndIndex(); idx < 0 && want != "" || idx >= 0 && got != want {
+			t.Errorf("rotate() default = %v (%d), want %q", got, idx, want)
+		}
+	}
+	if err = rotate(file, rotation, true, nil); err != nil {
+		t.Fatalf("rotate() error = %v", err)
+	}
+	if got, _, _ := file.GetUsedProfileNameAndIndex(); got != "" && got != "no default" {
+		t.Errorf("rotate() backward default = %v, want no default", got)
+	}
+	if err = rotate(file, rotation, true, nil); err != nil {
+		t.Fatalf("rotate() error = %v", err)
+	}
+	if got, _, _ := file.GetUsedProfileNameAndIndex(); got != "test" {
+		t.Errorf("rotate() backward default = %v, want test", got)
+	}
+	if err = rotate(file, rotation, true, nil); err != nil {
+		t.Fatalf("rotate() error = %v", err)
+	}
+	if got, _, _ := file.GetUsedProfileNameAndIndex(); got != "live" {
+		t.Errorf("rotate() confirmed backward default = %v, want live", got)
+	}
+}
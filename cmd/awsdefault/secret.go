@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/peterbueschel/awsdefault"
+	"github.com/urfave/cli"
+)
+
+var revealFlags = []cli.Flag{
+	cli.BoolFlag{
+		Name:  "reveal",
+		Usage: "print the secrets in plain text instead of masked",
+	},
+	cli.BoolFlag{
+		Name:  "force",
+		Usage: "reveal the secrets also if the output is no terminal, e.g. a pipe or a file",
+	},
+}
+
+// revealSecrets tells whether the secrets are printed in plain text. Secrets are only revealed
+// with --reveal and into a terminal, unless --force is given as well.
+func revealSecrets(c *cli.Context, out *os.File) (bool, error) {
+	if !c.Bool("reveal") {
+		return false, nil
+	}
+	if !c.Bool("force") && !isTerminal(out) {
+		return false, fmt.Errorf("refusing to reveal secrets, the output is no terminal; add --force to reveal them anyway")
+	}
+	return true, nil
+}
+
+// maskProfile returns a copy of the profile, whose secrets are replaced by their masked form
+func maskProfile(p *awsdefault.Profile) *awsdefault.Profile {
+	m := *p
+	m.SecretAccessKey = awsdefault.Secret(p.SecretAccessKey.String())
+	m.SessionToken = awsdefault.Secret(p.SessionToken.String())
+	return &m
+}
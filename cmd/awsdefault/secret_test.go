@@ -0,0 +1,62 @@
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/peterbueschel/awsdefault"
+	"github.com/urfave/cli"
+)
+
+func Test_revealSecrets(t *testing.T) {
+	out, err := ioutil.TempFile("", "awsdefault")
+	if err != nil {
+		t.Fatalf("could not create temp file: %s", err)
+	}
+	defer os.Remove(out.Name())
+	defer out.Close()
+
+	tests := []struct {
+		name    string
+		args    []string
+		want    bool
+		wantErr bool
+	}{
+		{name: "0positiv - masked by default", args: nil, want: false},
+		{name: "1negativ - reveal into a file", args: []string{"--reveal"}, wantErr: true},
+		{name: "2positiv - forced reveal into a file", args: []string{"--reveal", "--force"}, want: true},
+		{name: "3positiv - force alone does not reveal", args: []string{"--force"}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			set := flag.NewFlagSet("key", flag.ContinueOnError)
+			for _, f := range revealFlags {
+				f.Apply(set)
+			}
+			if err := set.Parse(tt.args); err != nil {
+				t.Fatalf("could not parse flags: %s", err)
+			}
+			got, err := revealSecrets(cli.NewContext(cli.NewApp(), set, nil), out)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("revealSecrets() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("revealSecrets() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_maskProfile(t *testing.T) {
+	p := &awsdefault.Profile{AccessKeyID: "A", SecretAccessKey: "abcdefghijklmnopqrstuvwxyz0123456789ABCD"}
+	got := maskProfile(p)
+	if got.SecretAccessKey.Reveal() != "********ABCD" || got.SessionToken.Reveal() != "" {
+		t.Errorf("maskProfile() = %#v", got)
+	}
+	if p.SecretAccessKey.Reveal() == "********ABCD" {
+		t.Errorf("maskProfile() changed the original profile")
+	}
+}
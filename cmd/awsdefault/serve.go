@@ -0,0 +1,108 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+
+	"github.com/peterbueschel/awsdefault"
+	"github.com/urfave/cli"
+)
+
+const credentialsPath = "/credentials"
+
+// randomToken returns a new secret used to authorize requests against the local endpoints
+func randomToken() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// containerCredentialsHandler serves the credentials of the currently used profile at the
+// credentialsPath to clients sending the token
+func containerCredentialsHandler(token string) http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle(credentialsPath, awsdefault.NewContainerCredentialsHandler(token, awsdefault.LoadActiveProfile))
+	return mux
+}
+
+// instanceMetadataHandler serves the credentials and the region of the currently used profile as
+// instance profile with the role name
+func instanceMetadataHandler(role string) http.Handler {
+	return awsdefault.NewIMDS(role, awsdefault.LoadActiveProfile)
+}
+
+func serveContainerCredentials() *cli.Command {
+	return &cli.Command{
+		Name:  "serve",
+		Usage: "Serves the credentials of the currently used profile via the ECS container credentials protocol.",
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "listen, l",
+				Value: "127.0.0.1:9911",
+				Usage: "address of the local HTTP server",
+			},
+			cli.StringFlag{
+				Name:   "token, t",
+				Usage:  "authorization token expected from the clients; a random one by default",
+				EnvVar: "AWS_CONTAINER_AUTHORIZATION_TOKEN",
+			},
+		},
+		Action: func(c *cli.Context) (err error) {
+			token := c.String("token")
+			if len(token) == 0 {
+				if token, err = randomToken(); err != nil {
+					return err
+				}
+			}
+			l, err := net.Listen("tcp", c.String("listen"))
+			if err != nil {
+				return err
+			}
+			defer l.Close()
+			fmt.Printf(
+				"export AWS_CONTAINER_CREDENTIALS_FULL_URI='http://%s%s'\nexport AWS_CONTAINER_AUTHORIZATION_TOKEN='%s'\n",
+				l.Addr(), credentialsPath, token,
+			)
+			os.Stdout.Sync()
+			log.Printf("[AWSDEFAULT][INFO] serving container credentials on %s.\n", l.Addr())
+			return http.Serve(l, containerCredentialsHandler(token))
+		},
+	}
+}
+
+func serveInstanceMetadata() *cli.Command {
+	return &cli.Command{
+		Name:  "imds",
+		Usage: "Emulates the EC2 instance metadata service (IMDSv2) serving the credentials and the region of the currently used profile.",
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "listen, l",
+				Value: "127.0.0.1:1338",
+				Usage: "address of the local HTTP server",
+			},
+			cli.StringFlag{
+				Name:  "role, r",
+				Value: "awsdefault",
+				Usage: "name of the emulated instance profile role",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			l, err := net.Listen("tcp", c.String("listen"))
+			if err != nil {
+				return err
+			}
+			defer l.Close()
+			fmt.Printf("export AWS_EC2_METADATA_SERVICE_ENDPOINT='http://%s'\n", l.Addr())
+			os.Stdout.Sync()
+			log.Printf("[AWSDEFAULT][INFO] serving instance metadata on %s.\n", l.Addr())
+			return http.Serve(l, instanceMetadataHandler(c.String("role")))
+		},
+	}
+}
@@ -0,0 +1,339 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/peterbueschel/awsdefault"
+	"github.com/urfave/cli"
+)
+
+// supported status bars
+const (
+	barWaybar   = "waybar"
+	barI3blocks = "i3blocks"
+	barPolybar  = "polybar"
+	barTmux     = "tmux"
+)
+
+// expiringWithin marks temporary credentials, which expire soon
+const expiringWithin = 15 * time.Minute
+
+// countdownRefresh is the interval the stream renders the status again, which moves the countdown
+// of temporary credentials on; the countdown shows minutes
+const countdownRefresh = 30 * time.Second
+
+// status describes the default profile for a status bar
+type status struct {
+	name       string // empty, if no profile is set as default
+	region     string
+	color      string
+	production bool
+	expiry     *time.Time
+}
+
+func statusCommand(source *awsdefault.CredentialsFile, file awsdefault.Switcher) *cli.Command {
+	return &cli.Command{
+		Name:  "status",
+		Usage: "Shows the default profile with its environment colour and expiry in the format of a status bar.",
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "bar, b",
+				Usage: "format of the status bar: waybar, i3blocks, polybar or tmux; plain text without",
+			},
+			cli.BoolFlag{
+				Name:  "stream, s",
+				Usage: "keep running and print a new line whenever the status changes",
+			},
+			cli.StringFlag{
+				Name:  "empty",
+				Value: "--No Profile--",
+				Usage: "text shown, if no profile is set as default",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			bar, empty := c.String("bar"), c.String("empty")
+			if _, err := (status{}).render(bar, empty, time.Now()); err != nil {
+				return err
+			}
+			if !c.Bool("stream") {
+				s, err := readStatus(file, settingsOrNil())
+				if err != nil {
+					return err
+				}
+				line, _ := s.render(bar, empty, time.Now())
+				_, err = fmt.Fprintln(os.Stdout, line)
+				return err
+			}
+			changes, err := statusChanges(source, file, nil) // runs as long as the command
+			if err != nil {
+				return err
+			}
+			ticker := time.NewTicker(countdownRefresh)
+			defer ticker.Stop()
+			return streamStatus(os.Stdout, bar, empty, changes, ticker.C)
+		},
+	}
+}
+
+// settingsOrNil returns the settings; nil, if they cannot be read, which shows the status without
+// colours
+func settingsOrNil() *awsdefault.Settings {
+	s, err := awsdefault.GetSettings()
+	if err != nil {
+		log.Printf("[AWSDEFAULT][WARNING] %v.\n", err)
+		return nil
+	}
+	return s
+}
+
+// readStatus returns the status of the default profile; the settings add the environment colour
+func readStatus(file awsdefault.Switcher, settings *awsdefault.Settings) (status, error) {
+	if _, idx, err := file.GetUsedProfileNameAndIndex(); idx == -2 {
+		return status{}, err
+	}
+	n, p, err := awsdefault.GetActiveProfile(file)
+	if err != nil {
+		return status{}, err
+	}
+	info := p.Info(n, true)
+	s := status{name: n, region: info.Region, expiry: info.Expiry}
+	if settings != nil {
+		s.color = settings.Color(n)
+		s.production = settings.IsProduction(n)
+	}
+	return s, nil
+}
+
+// statusChanges returns the switchers to read the status from: the current one and another one
+// after every change, until done gets closed. A running awsdefaultd announces the changes;
+// otherwise the credentials file and the settings are watched and each change delivers the
+// switcher of the reloaded file. If awsdefaultd exits, the files are watched from then on. The
+// channel is closed after done or, if the changes cannot be followed any longer.
+func statusChanges(source *awsdefault.CredentialsFile, file awsdefault.Switcher, done <-chan struct{}) (<-chan awsdefault.Switcher, error) {
+	changes := make(chan awsdefault.Switcher)
+	c, ok := file.(*awsdefault.Client)
+	if !ok {
+		if err := watchStatus(source, file, changes, done); err != nil {
+			return nil, err
+		}
+		return changes, nil
+	}
+	events, err := c.Subscribe(done) // starts with the current state
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		for range events {
+			select {
+			case changes <- c:
+			case <-done:
+			}
+		}
+		select {
+		case <-done:
+			close(changes)
+			return
+		default:
+		}
+		log.Printf("[AWSDEFAULT][WARNING] lost the connection to awsdefaultd; watching %s instead.\n", source.Path)
+		local, err := awsdefault.OpenCredentialsFile()
+		if err == nil {
+			err = watchStatus(local, awsdefault.LocalSwitcher(local), changes, done)
+		}
+		if err != nil {
+			log.Printf("[AWSDEFAULT][ERROR] %v.\n", err)
+			close(changes)
+		}
+	}()
+	return changes, nil
+}
+
+// watchStatus sends the switcher and afterwards the switcher of the reloaded file after every
+// change of the credentials file or the settings; changes get closed, when the watch ends.
+func watchStatus(source *awsdefault.CredentialsFile, file awsdefault.Switcher, changes chan<- awsdefault.Switcher, done <-chan struct{}) error {
+	events, err := source.Watch(done)
+	if err != nil {
+		return err
+	}
+	send := func(sw awsdefault.Switcher) bool {
+		select {
+		case changes <- sw:
+			return true
+		case <-done:
+			return false
+		}
+	}
+	go func() {
+		defer close(changes)
+		if !send(file) {
+			return
+		}
+		for e := range events {
+			if e.Err != nil {
+				log.Printf("[AWSDEFAULT][WARNING] %v.\n", e.Err)
+				continue
+			}
+			if !send(awsdefault.LocalSwitcher(e.File)) {
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// streamStatus writes the status line, whenever it differs from the last written one. The status
+// is read from every switcher received via changes; the ticks render the last status again to
+// move the countdown on. It returns, when the bar stops reading or the changes are closed, which
+// means the status is not followed any longer; both are errors. Errors while reading are logged and
+// keep the last line.
+func streamStatus(w io.Writer, bar, empty string, changes <-chan awsdefault.Switcher, ticks <-chan time.Time) error {
+	var (
+		s    status
+		read bool // s holds a status
+		last string
+	)
+	for {
+		now := time.Now()
+		select {
+		case file, ok := <-changes:
+			if !ok {
+				return fmt.Errorf("the changes of the default profile cannot be followed any longer")
+			}
+			curr, err := readStatus(file, settingsOrNil())
+			if err != nil {
+				log.Printf("[AWSDEFAULT][WARNING] %v.\n", err)
+				continue
+			}
+			s, read = curr, true
+		case t, ok := <-ticks:
+			if !ok {
+				ticks = nil
+			}
+			if !ok || !read {
+				continue
+			}
+			now = t
+		}
+		line, err := s.render(bar, empty, now)
+		if err != nil {
+			return err
+		}
+		if line != last {
+			if _, err = fmt.Fprintln(w, line); err != nil {
+				return err
+			}
+			last = line
+		}
+	}
+}
+
+// text returns the name of the profile followed by the time left of temporary credentials
+func (s status) text(empty string, now time.Time) string {
+	switch {
+	case len(s.name) == 0:
+		return empty
+	case s.expiry != nil:
+		return fmt.Sprintf("%s (%s)", s.name, awsdefault.Countdown(*s.expiry, now))
+	}
+	return s.name
+}
+
+// tooltip describes the profile in detail, one property per line
+func (s status) tooltip(empty string, now time.Time) string {
+	if len(s.name) == 0 {
+		return empty
+	}
+	lines := []string{"Profile: " + s.name}
+	if len(s.region) > 0 {
+		lines = append(lines, "Region: "+s.region)
+	}
+	if s.expiry != nil {
+		lines = append(lines, fmt.Sprintf("Expires: %s (%s)",
+			s.expiry.Local().Format("2006-01-02 15:04"), awsdefault.Countdown(*s.expiry, now)))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// classes returns the CSS classes of the waybar module: none or profile, production, and expired or
+// expiring for temporary credentials
+func (s status) classes(now time.Time) []string {
+	if len(s.name) == 0 {
+		return []string{"none"}
+	}
+	classes := []string{"profile"}
+	if s.production {
+		classes = append(classes, "production")
+	}
+	if s.expiry != nil {
+		switch left := s.expiry.Sub(now); {
+		case left <= 0:
+			classes = append(classes, "expired")
+		case left < expiringWithin:
+			classes = append(classes, "expiring")
+		}
+	}
+	return classes
+}
+
+// render returns the status as a single line in the native format of the bar
+func (s status) render(bar, empty string, now time.Time) (string, error) {
+	text := s.text(empty, now)
+	switch bar {
+	case "":
+		return text, nil
+	case barWaybar:
+		if len(s.color) > 0 {
+			text = fmt.Sprintf(`<span color="%s">%s</span>`, s.color, html.EscapeString(text))
+		} else {
+			text = html.EscapeString(text)
+		}
+		return marshalLine(struct {
+			Text    string   `json:"text"`
+			Tooltip string   `json:"tooltip"`
+			Class   []string `json:"class"`
+		}{text, html.EscapeString(s.tooltip(empty, now)), s.classes(now)})
+	case barI3blocks:
+		short := s.name
+		if len(short) == 0 {
+			short = empty
+		}
+		return marshalLine(struct {
+			FullText  string `json:"full_text"`
+			ShortText string `json:"short_text"`
+			Color     string `json:"color,omitempty"`
+		}{text, short, s.color})
+	case barPolybar:
+		text = strings.Replace(text, "%{", "%%{", -1) // a name must not start a format tag
+		if len(s.color) > 0 {
+			return fmt.Sprintf("%%{F%s}%s%%{F-}", s.color, text), nil
+		}
+		return text, nil
+	case barTmux:
+		text = strings.Replace(text, "#", "##", -1)
+		if len(s.color) > 0 {
+			return fmt.Sprintf("#[fg=%s]%s#[fg=default]", s.color, text), nil
+		}
+		return text, nil
+	}
+	return "", fmt.Errorf("unknown status bar %q, want %s, %s, %s or %s",
+		bar, barWaybar, barI3blocks, barPolybar, barTmux)
+}
+
+// marshalLine encodes v as JSON without a line break; the markup of waybar stays readable
+func marshalLine(v interface{}) (string, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"bytes"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-ini/ini"
+	"github.com/peterbueschel/awsdefault"
+)
+
+func Test_status_render(t *testing.T) {
+	now := time.Date(2019, 3, 1, 12, 0, 0, 0, time.UTC)
+	expiry := now.Add(10 * time.Minute)
+	live := status{name: "live", region: "eu-central-1", color: "#e01b24", production: true}
+	session := status{name: "dev-session", expiry: &expiry}
+	tests := []struct {
+		name   string
+		bar    string
+		status status
+		want   string
+	}{
+		{name: "positive — plain text", status: session, want: "dev-session (10m)"},
+		{name: "positive — plain text without profile", want: "--No Profile--"},
+		{
+			name:   "positive — waybar",
+			bar:    barWaybar,
+			status: live,
+			want:   `{"text":"<span color=\"#e01b24\">live</span>","tooltip":"Profile: live\nRegion: eu-central-1","class":["profile","production"]}`,
+		},
+		{
+			name:   "positive — waybar expiring",
+			bar:    barWaybar,
+			status: session,
+			want:   `{"text":"dev-session (10m)","tooltip":"Profile: dev-session\nExpires: ` + expiry.Local().Format("2006-01-02 15:04") + ` (10m)","class":["profile","expiring"]}`,
+		},
+		{
+			name: "positive — waybar without profile",
+			bar:  barWaybar,
+			want: `{"text":"--No Profile--","tooltip":"--No Profile--","class":["none"]}`,
+		},
+		{
+			name:   "positive — i3blocks",
+			bar:    barI3blocks,
+			status: live,
+			want:   `{"full_text":"live","short_text":"live","color":"#e01b24"}`,
+		},
+		{
+			name:   "positive — i3blocks without colour",
+			bar:    barI3blocks,
+			status: session,
+			want:   `{"full_text":"dev-session (10m)","short_text":"dev-session"}`,
+		},
+		{name: "positive — polybar", bar: barPolybar, status: live, want: "%{F#e01b24}live%{F-}"},
+		{name: "positive — polybar without colour", bar: barPolybar, status: session, want: "dev-session (10m)"},
+		{
+			name:   "positive — polybar escapes format tags",
+			bar:    barPolybar,
+			status: status{name: "x%{F#fff}y", color: "#e01b24"},
+			want:   "%{F#e01b24}x%%{F#fff}y%{F-}",
+		},
+		{name: "positive — tmux", bar: barTmux, status: live, want: "#[fg=#e01b24]live#[fg=default]"},
+		{name: "negative — unknown bar", bar: "xmobar", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.status.render(tt.bar, "--No Profile--", now)
+			if (err != nil) != (tt.bar == "xmobar") {
+				t.Fatalf("render() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("render() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_readStatus(t *testing.T) {
+	content, _ := ini.InsensitiveLoad([]byte(
+		"[default]\naws_access_key_id=A\naws_secret_access_key=B\nregion=eu-west-1\n" +
+			"[live]\naws_access_key_id=A\naws_secret_access_key=B\nregion=eu-central-1\n" +
+			"[dev]\naws_access_key_id=C\naws_secret_access_key=D\n",
+	))
+	file := &awsdefault.CredentialsFile{Content: content}
+	got, err := readStatus(file, nil)
+	if err != nil {
+		t.Fatalf("readStatus() error = %v", err)
+	}
+	if want := (status{name: "live", region: "eu-west-1"}); got != want {
+		t.Errorf("readStatus() = %+v, want %+v", got, want)
+	}
+	content.DeleteSection("default")
+	if got, err = readStatus(file, nil); err != nil || len(got.name) > 0 {
+		t.Errorf("readStatus() without default = %+v, %v", got, err)
+	}
+}
+
+func Test_statusChanges(t *testing.T) {
+	home := tempHome(t, testCredentials)
+	useHome(t, home)
+	file, err := awsdefault.OpenCredentialsFile()
+	if err != nil {
+		t.Fatalf("could not load the credentials: %v", err)
+	}
+	done := make(chan struct{})
+	defer close(done)
+	changes, err := statusChanges(file, awsdefault.LocalSwitcher(file), done)
+	if err != nil {
+		t.Fatalf("statusChanges() error = %v", err)
+	}
+	next := func() string {
+		select {
+		case sw := <-changes:
+			n, _, _ := sw.GetUsedProfileNameAndIndex()
+			return n
+		case <-time.After(5 * time.Second):
+			t.Fatalf("statusChanges() delivered nothing")
+		}
+		return ""
+	}
+	if got := next(); got != "dev" {
+		t.Errorf("statusChanges() first default = %v, want dev", got)
+	}
+	if _, err = runApp(t, home, "set", "live"); err != nil {
+		t.Fatalf("set live error = %v", err)
+	}
+	if got := next(); got != "live" {
+		t.Errorf("statusChanges() default after the switch = %v, want live", got)
+	}
+}
+
+func Test_statusChanges_daemon(t *testing.T) {
+	home := tempHome(t, testCredentials)
+	useHome(t, home)
+	file, err := awsdefault.OpenCredentialsFile()
+	if err != nil {
+		t.Fatalf("could not load the credentials: %v", err)
+	}
+	socket := filepath.Join(home, "awsdefault.sock")
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatalf("could not listen on %s: %v", socket, err)
+	}
+	defer l.Close()
+	go awsdefault.NewServer(file).Serve(l)
+	c, err := awsdefault.Dial(socket)
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	done := make(chan struct{})
+	defer close(done)
+	changes, err := statusChanges(file, c, done)
+	if err != nil {
+		t.Fatalf("statusChanges() error = %v", err)
+	}
+	next := func() awsdefault.Switcher {
+		select {
+		case sw, ok := <-changes:
+			if !ok {
+				t.Fatalf("statusChanges() closed the changes")
+			}
+			return sw
+		case <-time.After(5 * time.Second):
+			t.Fatalf("statusChanges() delivered nothing")
+		}
+		return nil
+	}
+	if sw := next(); sw != c {
+		t.Errorf("statusChanges() first switcher = %T, want the client", sw)
+	}
+
+	c.Close() // like an exited awsdefaultd
+	if n, _, _ := next().GetUsedProfileNameAndIndex(); n != "dev" {
+		t.Errorf("statusChanges() default after awsdefaultd exited = %v, want dev", n)
+	}
+	if _, err = runApp(t, home, "set", "live"); err != nil {
+		t.Fatalf("set live error = %v", err)
+	}
+	if n, _, _ := next().GetUsedProfileNameAndIndex(); n != "live" {
+		t.Errorf("statusChanges() default after the switch = %v, want live", n)
+	}
+}
+
+func Test_streamStatus(t *testing.T) {
+	useHome(t, tempHome(t, ""))
+	expiry := time.Now().Add(2 * time.Hour).UTC()
+	switcher := func(def string) awsdefault.Switcher {
+		content, _ := ini.InsensitiveLoad([]byte(def +
+			"[dev]\naws_access_key_id=A\n" +
+			"[live]\naws_access_key_id=B\naws_expiration=" + expiry.Format(time.RFC3339) + "\n",
+		))
+		return &awsdefault.CredentialsFile{Content: content}
+	}
+	dev, none := switcher("[default]\naws_access_key_id=A\n"), switcher("")
+	live := switcher("[default]\naws_access_key_id=B\naws_expiration=" + expiry.Format(time.RFC3339) + "\n")
+
+	changes, ticks := make(chan awsdefault.Switcher), make(chan time.Time)
+	go func() {
+		ticks <- time.Now() // nothing read yet
+		for _, f := range []awsdefault.Switcher{dev, dev, none, live} {
+			changes <- f
+		}
+		ticks <- expiry.Add(time.Second)
+		close(ticks)
+		close(changes)
+	}()
+	var buf bytes.Buffer
+	if err := streamStatus(&buf, barPolybar, "none", changes, ticks); err == nil {
+		t.Errorf("streamStatus() no error after the changes were closed")
+	}
+	if got, want := buf.String(), "dev\nnone\nlive (1h59m)\nlive (expired)\n"; got != want {
+		t.Errorf("streamStatus() = %q, want %q", got, want)
+	}
+
+	changes = make(chan awsdefault.Switcher, 1)
+	changes <- dev
+	if err := streamStatus(&buf, "xmobar", "none", changes, nil); err == nil || !strings.Contains(err.Error(), "xmobar") {
+		t.Errorf("streamStatus() of an unknown bar error = %v", err)
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/peterbueschel/awsdefault"
+	"github.com/urfave/cli"
+)
+
+func tagProfile(file awsdefault.Switcher) *cli.Command {
+	return &cli.Command{
+		Name:      "tag",
+		Aliases:   []string{"tags"},
+		Usage:     "Shows, adds or removes the tags of a profile used to select profiles, e.g. via 'each --tag'; '--color' sets its environment colour.",
+		ArgsUsage: "<profile> [tag...]",
+		BashComplete: func(c *cli.Context) {
+			if c.NArg() == 0 {
+				completeProfiles(file)(c)
+				return
+			}
+			completeTags(c.App.Writer)
+		},
+		Flags: []cli.Flag{
+			cli.BoolFlag{
+				Name:  "remove, r",
+				Usage: "remove the given tags instead of adding them",
+			},
+			cli.StringFlag{
+				Name:  "color, c",
+				Usage: "colour of the profile as #rgb or #rrggbb, which overrides the colour of its environment tag; empty removes it",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			if c.NArg() < 1 {
+				return fmt.Errorf("the name of the profile is required")
+			}
+			name := c.Args().First()
+			if _, err := file.GetProfileBy(name); err != nil {
+				return err
+			}
+			s, err := awsdefault.GetSettings()
+			if err != nil {
+				return err
+			}
+			if c.IsSet("color") {
+				if err = s.SetColor(name, c.String("color")); err != nil {
+					return err
+				}
+			}
+			tags := s.Tags(name)
+			if c.NArg() < 2 {
+				if !c.IsSet("color") {
+					fmt.Println(strings.Join(tags, " "))
+				}
+				return nil
+			}
+			if !c.Bool("remove") {
+				return s.SetTags(name, append(tags, c.Args().Tail()...))
+			}
+			var kept []string
+			for _, t := range tags {
+				if !contains(c.Args().Tail(), t) {
+					kept = append(kept, t)
+				}
+			}
+			return s.SetTags(name, kept)
+		},
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
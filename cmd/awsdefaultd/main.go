@@ -0,0 +1,110 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"github.com/peterbueschel/awsdefault"
+)
+
+var (
+	socket string
+)
+
+// listen creates the Unix socket, which is only accessible by the current user. The socket is
+// created inside a directory only accessible by the user, hence no other user can connect between
+// its creation and the chmod; a missing directory is created. A socket left behind by a crashed
+// daemon gets removed; a socket of a running daemon or any other file at the path is an error.
+func listen(path string) (net.Listener, error) {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	info, err := os.Lstat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is no directory", dir)
+	}
+	if info.Mode().Perm()&0077 != 0 {
+		return nil, fmt.Errorf("%s is accessible by other users (%04o); choose a socket inside a private directory", dir, info.Mode().Perm())
+	}
+	if c, err := awsdefault.Dial(path); err == nil {
+		c.Close()
+		return nil, fmt.Errorf("awsdefaultd is already listening on %s", path)
+	}
+	if info, err = os.Lstat(path); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("%s exists and is no socket", path)
+		}
+		if err = os.Remove(path); err != nil {
+			return nil, err
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err = os.Chmod(path, 0600); err != nil {
+		l.Close()
+		return nil, err
+	}
+	return l, nil
+}
+
+func init() {
+	flag.StringVar(&socket, "socket", awsdefault.SocketPath(), "path of the Unix socket")
+}
+
+func main() {
+	flag.Parse()
+	file, err := awsdefault.GetCredentialsFile()
+	if err != nil {
+		log.Fatalf("[AWSDEFAULTD][ERROR] %v.\n", err)
+	}
+	l, err := listen(socket)
+	if err != nil {
+		log.Fatalf("[AWSDEFAULTD][ERROR] %v.\n", err)
+	}
+
+	sig := make(chan os.Signal, 1)
+	stopped := make(chan struct{})
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sig
+		close(stopped)
+		l.Close() // also removes the socket file
+	}()
+
+	server := awsdefault.NewServer(file)
+	events, err := file.Watch(stopped)
+	if err != nil {
+		log.Fatalf("[AWSDEFAULTD][ERROR] %v.\n", err)
+	}
+	go func() {
+		for e := range events {
+			if e.Err != nil {
+				log.Printf("[AWSDEFAULTD][ERROR] %v.\n", e.Err)
+				continue
+			}
+			server.Reload(e.File)
+		}
+	}()
+
+	log.Printf("[AWSDEFAULTD][INFO] listening on %s.\n", socket)
+	err = server.Serve(l)
+	select {
+	case <-stopped:
+	default:
+		log.Fatalf("[AWSDEFAULTD][ERROR] %v.\n", err)
+	}
+}
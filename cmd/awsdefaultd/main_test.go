@@ -0,0 +1,86 @@
+package main
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_listen(t *testing.T) {
+	dir, err := ioutil.TempDir("", "awsdefaultd")
+	if err != nil {
+		t.Fatalf("listen(): could not create temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	shared := filepath.Join(dir, "shared")
+	if err = os.Mkdir(shared, 0755); err != nil {
+		t.Fatalf("listen(): could not create shared dir: %s", err)
+	}
+	stale := filepath.Join(dir, "stale.sock")
+	l, err := net.Listen("unix", stale)
+	if err != nil {
+		t.Fatalf("listen(): could not create stale socket: %s", err)
+	}
+	l.(*net.UnixListener).SetUnlinkOnClose(false) // like a crashed daemon
+	l.Close()
+	credentials := filepath.Join(dir, "credentials")
+	if err = ioutil.WriteFile(credentials, []byte("[default]\n"), 0600); err != nil {
+		t.Fatalf("listen(): could not create credentials file: %s", err)
+	}
+	tests := []struct {
+		name    string
+		path    string
+		wantErr bool
+	}{
+		{
+			name: "positive — BAT",
+			path: filepath.Join(dir, "awsdefault.sock"),
+		},
+		{
+			name: "positive — stale socket gets replaced",
+			path: stale,
+		},
+		{
+			name: "positive — missing directory gets created",
+			path: filepath.Join(dir, "xxxxxxxx", "awsdefault.sock"),
+		},
+		{
+			name:    "negative — other file at the path is kept",
+			path:    credentials,
+			wantErr: true,
+		},
+		{
+			name:    "negative — directory accessible by other users",
+			path:    filepath.Join(shared, "awsdefault.sock"),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, err := listen(tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("listen() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err != nil {
+				return
+			}
+			defer l.Close()
+			if _, err = listen(tt.path); err == nil {
+				t.Errorf("listen() no error although the socket is in use")
+			}
+			info, err := os.Stat(tt.path)
+			if err != nil || info.Mode().Perm() != 0600 {
+				t.Errorf("listen() socket mode = %v, error = %v", info.Mode(), err)
+			}
+			if info, err = os.Stat(filepath.Dir(tt.path)); err != nil || info.Mode().Perm() != 0700 {
+				t.Errorf("listen() directory mode = %v, error = %v", info.Mode(), err)
+			}
+		})
+	}
+	if content, err := ioutil.ReadFile(credentials); err != nil || string(content) != "[default]\n" {
+		t.Errorf("listen() changed the credentials file: %q, error = %v", content, err)
+	}
+}
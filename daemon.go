@@ -0,0 +1,379 @@
+//Copyright 2018 Peter Büschel
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package awsdefault
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/go-ini/ini"
+)
+
+// operations understood by the awsdefaultd daemon
+const (
+	OpList      = "list"
+	OpGet       = "get"
+	OpSet       = "set"
+	OpUnset     = "unset"
+	OpReload    = "reload"
+	OpSubscribe = "subscribe"
+)
+
+type (
+	// Switcher lists the available profiles and changes the default profile. It is implemented by
+	// the CredentialsFile itself and by the Client of a running awsdefaultd daemon.
+	Switcher interface {
+		GetProfilesNames() []string
+		GetUsedProfileNameAndIndex() (string, int, error)
+		GetProfileBy(name string) (*Profile, error)
+		SetDefaultTo(profileName string) error
+		UnSetDefault() error
+	}
+
+	// Request is sent by a client to the daemon; one JSON object per line.
+	Request struct {
+		Op      string `json:"op"`
+		Profile string `json:"profile,omitempty"`
+	}
+
+	// Response is the answer of the daemon to a single Request.
+	Response struct {
+		Error    string            `json:"error,omitempty"`
+		Profiles []string          `json:"profiles,omitempty"`
+		Current  string            `json:"current,omitempty"`
+		Index    int               `json:"index"`
+		Keys     map[string]string `json:"keys,omitempty"`
+	}
+
+	// Event is pushed to all subscribers whenever the default profile changed.
+	Event struct {
+		Current string `json:"current"`
+		Index   int    `json:"index"`
+	}
+
+	// Server serves a CredentialsFile to clients connected via a Unix socket.
+	Server struct {
+		mu          sync.Mutex
+		file        *CredentialsFile
+		subscribers map[chan Event]bool
+	}
+
+	// Client talks to a running awsdefaultd daemon.
+	Client struct {
+		mu   sync.Mutex
+		path string
+		conn net.Conn
+		r    *bufio.Reader
+		subs []net.Conn
+	}
+)
+
+// SocketPath returns the path of the Unix socket used by the awsdefaultd daemon. It can be set via
+// the environment variable AWSDEFAULT_SOCKET and defaults to a socket inside XDG_RUNTIME_DIR or
+// inside a per-user directory of the temporary directory.
+func SocketPath() string {
+	if p := os.Getenv("AWSDEFAULT_SOCKET"); len(p) > 0 {
+		return p
+	}
+	if d := os.Getenv("XDG_RUNTIME_DIR"); len(d) > 0 {
+		return filepath.Join(d, "awsdefault.sock")
+	}
+	return filepath.Join(os.TempDir(), fmt.Sprintf("awsdefault-%d", os.Getuid()), "awsdefault.sock")
+}
+
+// NewSwitcher returns a Client, if an awsdefaultd daemon is listening on the SocketPath, otherwise
+// it falls back to the LocalSwitcher of the given CredentialsFile.
+func NewSwitcher(file *CredentialsFile) Switcher {
+	if c, err := Dial(SocketPath()); err == nil {
+		return c
+	}
+	return LocalSwitcher(file)
+}
+
+// NewServer returns a Server holding the given CredentialsFile.
+func NewServer(file *CredentialsFile) *Server {
+	return &Server{file: file, subscribers: make(map[chan Event]bool)}
+}
+
+// Reload replaces the served CredentialsFile, e.g. after it was changed on disk, and informs all
+// subscribers.
+func (s *Server) Reload(file *CredentialsFile) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.file = file
+	s.broadcast()
+}
+
+// Serve accepts connections on the listener until it gets closed.
+func (s *Server) Serve(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go s.handle(conn)
+	}
+}
+
+// handle answers the requests of a single connection
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+	dec := json.NewDecoder(conn)
+	enc := json.NewEncoder(conn)
+	for {
+		var req Request
+		if err := dec.Decode(&req); err != nil {
+			return
+		}
+		if req.Op == OpSubscribe {
+			s.stream(conn, enc)
+			return
+		}
+		if err := enc.Encode(s.answer(req)); err != nil {
+			return
+		}
+	}
+}
+
+// answer executes a single request against the CredentialsFile
+func (s *Server) answer(req Request) (resp Response) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var err error
+	sw := LocalSwitcher(s.file)
+	switch req.Op {
+	case OpList:
+		resp.Profiles = sw.GetProfilesNames()
+	case OpGet:
+		if len(req.Profile) > 0 {
+			var p *Profile
+			if p, err = sw.GetProfileBy(req.Profile); err == nil {
+				resp.Keys = p.keys
+			}
+			break
+		}
+		resp.Current, resp.Index, err = sw.GetUsedProfileNameAndIndex()
+	case OpSet:
+		if err = sw.SetDefaultTo(req.Profile); err == nil {
+			s.broadcast()
+		}
+	case OpUnset:
+		if err = sw.UnSetDefault(); err == nil {
+			s.broadcast()
+		}
+	case OpReload:
+		var file *CredentialsFile
+		if file, err = loadCredentialsFile(s.file.Path); err == nil {
+			s.file = file
+			s.broadcast()
+		}
+	default:
+		err = fmt.Errorf("unknown operation %q", req.Op)
+	}
+	if err != nil {
+		resp.Error = err.Error()
+	}
+	return resp
+}
+
+// stream sends the current state and afterwards every change to the subscriber, until the
+// connection gets closed
+func (s *Server) stream(conn net.Conn, enc *json.Encoder) {
+	events := make(chan Event, 1)
+	s.mu.Lock()
+	s.subscribers[events] = true
+	events <- s.event()
+	s.mu.Unlock()
+
+	closed := make(chan struct{})
+	go func() { // a subscriber never sends again; a read returns only if the client hangs up
+		_, _ = conn.Read(make([]byte, 1))
+		close(closed)
+	}()
+	defer func() {
+		s.mu.Lock()
+		delete(s.subscribers, events)
+		s.mu.Unlock()
+	}()
+	for {
+		select {
+		case e := <-events:
+			if err := enc.Encode(e); err != nil {
+				return
+			}
+		case <-closed:
+			return
+		}
+	}
+}
+
+// event describes the current default profile; the caller must hold the lock
+func (s *Server) event() Event {
+	n, idx, _ := LocalSwitcher(s.file).GetUsedProfileNameAndIndex()
+	return Event{Current: n, Index: idx}
+}
+
+// broadcast informs all subscribers about the current default profile; the caller must hold
+// the lock. Slow subscribers only get the latest event.
+func (s *Server) broadcast() {
+	e := s.event()
+	for ch := range s.subscribers {
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- e
+	}
+}
+
+// Dial connects to the awsdefaultd daemon listening on the given socket.
+func Dial(path string) (*Client, error) {
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{path: path, conn: conn, r: bufio.NewReader(conn)}, nil
+}
+
+// Close closes the connection to the daemon and ends all subscriptions.
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, s := range c.subs {
+		s.Close()
+	}
+	return c.conn.Close()
+}
+
+// do sends a single request to the daemon and waits for the response
+func (c *Client) do(req Request) (resp Response, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err = json.NewEncoder(c.conn).Encode(req); err != nil {
+		return resp, err
+	}
+	line, err := c.r.ReadBytes('\n')
+	if err != nil {
+		return resp, err
+	}
+	if err = json.Unmarshal(line, &resp); err != nil {
+		return resp, err
+	}
+	if len(resp.Error) > 0 {
+		err = errors.New(resp.Error)
+	}
+	return resp, err
+}
+
+// GetProfilesNames returns a sorted list of all available profiles known by the daemon. The
+// Switcher offers no way to return an error, hence a failed request is logged and returns no
+// profiles.
+func (c *Client) GetProfilesNames() []string {
+	resp, err := c.do(Request{Op: OpList})
+	if err != nil {
+		log.Printf("[AWSDEFAULT][ERROR] could not list the profiles of awsdefaultd: %v.\n", err)
+	}
+	return resp.Profiles
+}
+
+// GetUsedProfileNameAndIndex returns the name and the index of the profile currently used as
+// default profile.
+func (c *Client) GetUsedProfileNameAndIndex() (string, int, error) {
+	resp, err := c.do(Request{Op: OpGet})
+	return resp.Current, resp.Index, err
+}
+
+// GetProfileBy returns the profile by a given name
+func (c *Client) GetProfileBy(name string) (*Profile, error) {
+	resp, err := c.do(Request{Op: OpGet, Profile: name})
+	if err != nil {
+		return &Profile{keys: make(map[string]string)}, err
+	}
+	s, _ := ini.Empty().NewSection(name)
+	for k, v := range resp.Keys {
+		_, _ = s.NewKey(k, v)
+	}
+	return profileFrom(s), nil
+}
+
+// SetDefaultTo lets the daemon change the default profile.
+func (c *Client) SetDefaultTo(profileName string) error {
+	_, err := c.do(Request{Op: OpSet, Profile: profileName})
+	return err
+}
+
+// UnSetDefault lets the daemon delete the default profile.
+func (c *Client) UnSetDefault() error {
+	_, err := c.do(Request{Op: OpUnset})
+	return err
+}
+
+// Reload lets the daemon read the credentials file again. A client, which changed the file itself,
+// e.g. via StoreSession, calls it before further requests; otherwise the daemon only follows
+// after its watcher noticed the change and a switch in the meantime would write the old content.
+func (c *Client) Reload() error {
+	_, err := c.do(Request{Op: OpReload})
+	return err
+}
+
+// Subscribe opens a second connection to the daemon and returns a channel receiving the current
+// default profile and afterwards every change of it. The channel gets closed together with the
+// connection to the daemon; closing done ends the subscription and its connection.
+func (c *Client) Subscribe(done <-chan struct{}) (<-chan Event, error) {
+	conn, err := net.Dial("unix", c.path)
+	if err != nil {
+		return nil, err
+	}
+	if err = json.NewEncoder(conn).Encode(Request{Op: OpSubscribe}); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	c.mu.Lock()
+	c.subs = append(c.subs, conn)
+	c.mu.Unlock()
+	events := make(chan Event)
+	finished := make(chan struct{})
+	go func() {
+		select { // unblocks the decoder below
+		case <-done:
+		case <-finished:
+		}
+		conn.Close()
+	}()
+	go func() {
+		defer close(events)
+		defer close(finished)
+		dec := json.NewDecoder(conn)
+		for {
+			var e Event
+			if err := dec.Decode(&e); err != nil {
+				return
+			}
+			select {
+			case events <- e:
+			case <-done:
+				return
+			}
+		}
+	}()
+	return events, nil
+}
@@ -0,0 +1,227 @@
+package awsdefault
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-ini/ini"
+	"github.com/kylelemons/godebug/pretty"
+)
+
+// startTestServer serves a copy of the test credentials file on a temporary socket
+func startTestServer(t *testing.T) (*Server, string, func()) {
+	dir, err := ioutil.TempDir("", "awsdefault")
+	if err != nil {
+		t.Fatalf("could not create temp dir: %s", err)
+	}
+	path := filepath.Join(dir, "credentials")
+	if err = ioutil.WriteFile(path, testFileContent, 0600); err != nil {
+		t.Fatalf("could not write credentials file: %s", err)
+	}
+	content, err := ini.InsensitiveLoad(path)
+	if err != nil {
+		t.Fatalf("could not load credentials file: %s", err)
+	}
+	socket := filepath.Join(dir, "awsdefault.sock")
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatalf("could not listen on %s: %s", socket, err)
+	}
+	s := NewServer(&CredentialsFile{Content: content, Path: path})
+	go s.Serve(l)
+	return s, socket, func() {
+		l.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestSocketPath(t *testing.T) {
+	tests := []struct {
+		name    string
+		socket  string
+		runtime string
+		want    string
+	}{
+		{
+			name:    "0positiv - socket given by AWSDEFAULT_SOCKET",
+			socket:  "/tmp/test.sock",
+			runtime: "/run/user/1000",
+			want:    "/tmp/test.sock",
+		},
+		{
+			name:    "1positiv - socket inside XDG_RUNTIME_DIR",
+			runtime: "/run/user/1000",
+			want:    "/run/user/1000/awsdefault.sock",
+		},
+		{
+			name: "2positiv - socket inside a per-user directory",
+			want: filepath.Join(os.TempDir(), fmt.Sprintf("awsdefault-%d", os.Getuid()), "awsdefault.sock"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Setenv("AWSDEFAULT_SOCKET", tt.socket)
+			os.Setenv("XDG_RUNTIME_DIR", tt.runtime)
+			defer os.Unsetenv("AWSDEFAULT_SOCKET")
+			if got := SocketPath(); got != tt.want {
+				t.Errorf("SocketPath() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewSwitcher(t *testing.T) {
+	_, socket, stop := startTestServer(t)
+	defer stop()
+	defer os.Unsetenv("AWSDEFAULT_SOCKET")
+
+	os.Setenv("AWSDEFAULT_SOCKET", socket)
+	if _, ok := NewSwitcher(&CredentialsFile{}).(*Client); !ok {
+		t.Errorf("NewSwitcher() did not return a client for a running daemon")
+	}
+	os.Setenv("AWSDEFAULT_SOCKET", socket+".missing")
+	if _, ok := NewSwitcher(&CredentialsFile{}).(*CredentialsFile); !ok {
+		t.Errorf("NewSwitcher() did not fall back to the credentials file")
+	}
+}
+
+func TestClient(t *testing.T) {
+	_, socket, stop := startTestServer(t)
+	defer stop()
+
+	c, err := Dial(socket)
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer c.Close()
+	events, err := c.Subscribe(nil)
+	if err != nil {
+		t.Fatalf("Client.Subscribe() error = %v", err)
+	}
+	if e := <-events; e.Current != "dev" {
+		t.Errorf("Client.Subscribe() first event = %+v, want dev", e)
+	}
+
+	if diff := pretty.Compare([]string{"dev", "live"}, c.GetProfilesNames()); diff != "" {
+		t.Errorf("Client.GetProfilesNames() diff: (-want +got)\n%s", diff)
+	}
+	p, err := c.GetProfileBy("live")
+	if err != nil || p.AccessKeyID != "ABCDEFGHIJK123456789" {
+		t.Errorf("Client.GetProfileBy() = %+v, error = %v", p, err)
+	}
+	if _, err = c.GetProfileBy("xxxxxxx"); err == nil {
+		t.Errorf("Client.GetProfileBy() no error for a missing profile")
+	}
+	if err = c.SetDefaultTo("xxxxxxx"); err == nil {
+		t.Errorf("Client.SetDefaultTo() no error for a missing profile")
+	}
+
+	if err = c.SetDefaultTo("live"); err != nil {
+		t.Fatalf("Client.SetDefaultTo() error = %v", err)
+	}
+	if e := <-events; e.Current != "live" || e.Index != 1 {
+		t.Errorf("Client.Subscribe() event = %+v, want live", e)
+	}
+	if n, idx, err := c.GetUsedProfileNameAndIndex(); n != "live" || idx != 1 || err != nil {
+		t.Errorf("Client.GetUsedProfileNameAndIndex() = %v, %v, %v", n, idx, err)
+	}
+
+	if err = c.UnSetDefault(); err != nil {
+		t.Fatalf("Client.UnSetDefault() error = %v", err)
+	}
+	if e := <-events; e.Index != -2 {
+		t.Errorf("Client.Subscribe() event = %+v, want no default", e)
+	}
+}
+
+func TestClient_Subscribe(t *testing.T) {
+	s, socket, stop := startTestServer(t)
+	defer stop()
+
+	c, err := Dial(socket)
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer c.Close()
+	done := make(chan struct{})
+	events, err := c.Subscribe(done)
+	if err != nil {
+		t.Fatalf("Client.Subscribe() error = %v", err)
+	}
+	// the consumer stops reading while the daemon keeps announcing changes
+	content, _ := ini.InsensitiveLoad([]byte("[default]\naws_access_key_id=A\n[new]\naws_access_key_id=A\n"))
+	s.Reload(&CredentialsFile{Content: content})
+	close(done)
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case _, ok := <-events:
+			if !ok {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("Client.Subscribe() channel not closed after done")
+		}
+	}
+}
+
+func TestServer_Reload(t *testing.T) {
+	s, socket, stop := startTestServer(t)
+	defer stop()
+
+	c, err := Dial(socket)
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer c.Close()
+	events, err := c.Subscribe(nil)
+	if err != nil {
+		t.Fatalf("Client.Subscribe() error = %v", err)
+	}
+	<-events // current state
+
+	content, _ := ini.InsensitiveLoad([]byte("[default]\naws_access_key_id=A\n[new]\naws_access_key_id=A\n"))
+	s.Reload(&CredentialsFile{Content: content})
+	if e := <-events; e.Current != "new" {
+		t.Errorf("Server.Reload() event = %+v, want new", e)
+	}
+	if diff := pretty.Compare([]string{"new"}, c.GetProfilesNames()); diff != "" {
+		t.Errorf("Server.Reload() diff: (-want +got)\n%s", diff)
+	}
+}
+
+func TestClient_Reload(t *testing.T) {
+	s, socket, stop := startTestServer(t)
+	defer stop()
+
+	c, err := Dial(socket)
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer c.Close()
+	// another process stores a profile and switches to it right away
+	file, err := loadCredentialsFile(s.file.Path)
+	if err != nil {
+		t.Fatalf("could not load credentials file: %s", err)
+	}
+	if err = file.AddProfile("new", &Profile{AccessKeyID: "N", SecretAccessKey: "S"}, false); err != nil {
+		t.Fatalf("AddProfile() error = %v", err)
+	}
+	if err = c.Reload(); err != nil {
+		t.Fatalf("Client.Reload() error = %v", err)
+	}
+	if err = c.SetDefaultTo("new"); err != nil {
+		t.Fatalf("Client.SetDefaultTo() after Client.Reload() error = %v", err)
+	}
+	if file, err = loadCredentialsFile(s.file.Path); err != nil {
+		t.Fatalf("could not load credentials file: %s", err)
+	}
+	if n, _, _ := file.GetUsedProfileNameAndIndex(); n != "new" {
+		t.Errorf("Client.SetDefaultTo() after Client.Reload() wrote default %v, want new", n)
+	}
+}
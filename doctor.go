@@ -0,0 +1,262 @@
+//Copyright 2018 Peter Büschel
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package awsdefault
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-ini/ini"
+)
+
+// severities of a finding
+const (
+	SeverityOK      = "ok"
+	SeverityWarning = "warning"
+	SeverityError   = "error"
+)
+
+// Finding is the result of one check of the setup.
+type Finding struct {
+	Check    string `json:"check"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+	Hint     string `json:"hint,omitempty"`
+}
+
+// doctor runs the checks against one state of the files and the environment
+type doctor struct {
+	file     *CredentialsFile
+	settings *Settings
+	config   *ini.File
+	env      map[string]string
+	now      time.Time
+	findings []Finding
+}
+
+func (d *doctor) report(check, severity, message, hint string) {
+	d.findings = append(d.findings, Finding{check, severity, message, hint})
+}
+
+// Diagnose checks the environment variables (in the form of os.Environ), the credentials file,
+// the AWS config file and the awsdefault settings for anything preventing the AWS cli and SDKs
+// from using the default profile.
+func Diagnose(file *CredentialsFile, environ []string) []Finding {
+	if file.Content == nil { // the credentials file is missing or could not be parsed
+		file = &CredentialsFile{Content: ini.Empty(), Path: file.Path}
+	}
+	d := &doctor{file: file, env: make(map[string]string), now: time.Now()}
+	for _, e := range environ {
+		if kv := strings.SplitN(e, "=", 2); len(kv) == 2 {
+			d.env[kv[0]] = kv[1]
+		}
+	}
+	d.settings, _ = GetSettings()
+	d.config, _ = ini.LoadSources(ini.LoadOptions{Loose: true}, configPath())
+
+	d.checkProfileEnv()
+	d.checkCredentialsEnv()
+	d.checkPermissions()
+	d.checkKeys()
+	d.checkDefault()
+	d.checkExpiry()
+	d.checkConfig()
+	return d.findings
+}
+
+func (d *doctor) checkProfileEnv() {
+	for _, k := range []string{"AWS_PROFILE", "AWS_DEFAULT_PROFILE"} {
+		v, ok := d.env[k]
+		switch {
+		case ok && v != "default":
+			d.report("profile_env", SeverityError,
+				fmt.Sprintf("%s is set to %q, hence the AWS cli ignores the default profile", k, v),
+				fmt.Sprintf("export %s=default", k))
+			return
+		case k == "AWS_PROFILE" && !ok:
+			d.report("profile_env", SeverityWarning,
+				"AWS_PROFILE is not set; a profile set by other tools or a shell takes precedence",
+				"export AWS_PROFILE=default")
+			return
+		}
+	}
+	d.report("profile_env", SeverityOK, "AWS_PROFILE is set to default", "")
+}
+
+func (d *doctor) checkCredentialsEnv() {
+	var set []string
+	for _, k := range []string{"AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY", "AWS_SESSION_TOKEN", "AWS_SECURITY_TOKEN"} {
+		if len(d.env[k]) > 0 {
+			set = append(set, k)
+		}
+	}
+	if len(set) > 0 {
+		d.report("credentials_env", SeverityError,
+			fmt.Sprintf("the environment contains %s, which take precedence over the default profile", strings.Join(set, ", ")),
+			"unset "+strings.Join(set, " "))
+		return
+	}
+	d.report("credentials_env", SeverityOK, "no credentials are set in the environment", "")
+}
+
+func (d *doctor) checkPermissions() {
+	if runtime.GOOS == "windows" {
+		return
+	}
+	if _, err := os.Stat(d.file.Path); err != nil {
+		d.report("permissions", SeverityError, err.Error(), "create the credentials file, e.g. via 'awsdefault import'")
+		return
+	}
+	if err := CheckPermissions(d.file.Path); err != nil {
+		d.report("permissions", SeverityError, err.Error(), "awsdefault fix-perms")
+		return
+	}
+	d.report("permissions", SeverityOK, fmt.Sprintf("%s is only accessible by its owner", d.file.Path), "")
+}
+
+func (d *doctor) checkKeys() {
+	ok := true
+	byID := make(map[string][]string)
+	for _, n := range d.file.GetProfilesNames() {
+		p, _ := d.file.GetProfileBy(n)
+		if len(p.AccessKeyID) == 0 {
+			continue
+		}
+		byID[p.AccessKeyID] = append(byID[p.AccessKeyID], n)
+		if err := ValidateKeys(p); err != nil {
+			ok = false
+			d.report("key_format", SeverityWarning,
+				fmt.Sprintf("the keys of the profile %s look malformed: %v", n, err),
+				fmt.Sprintf("check the keys of %s in the IAM console and import them again", n))
+		}
+	}
+	var ids []string
+	for id := range byID {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		if names := byID[id]; len(names) > 1 {
+			ok = false
+			d.report("duplicate_keys", SeverityWarning,
+				fmt.Sprintf("the profiles %s use the same AWS_ACCESS_KEY_ID %s", strings.Join(names, ", "), MaskKeyID(id)),
+				"remove the duplicated profiles; switching between them cannot be told apart")
+		}
+	}
+	if ok {
+		d.report("key_format", SeverityOK, "all keys are well-formed and unique", "")
+	}
+}
+
+func (d *doctor) checkDefault() {
+	if d.settings != nil && d.settings.Mode() == ModeCredentialProcess {
+		sel := &Selection{CredentialsFile: d.file, Settings: d.settings}
+		if _, idx, err := sel.GetUsedProfileNameAndIndex(); idx == -1 {
+			d.report("default", SeverityError, err.Error(), "awsdefault to <profile>")
+			return
+		}
+		if _, err := d.file.Content.GetSection("default"); err == nil {
+			d.report("default", SeverityError,
+				"the credentials file contains a default section, which wins over the credential_process",
+				"awsdefault mode default && awsdefault mode credential_process")
+			return
+		}
+		d.report("default", SeverityOK, "the credential_process mode is used", "")
+		return
+	}
+	n, idx, err := d.file.GetUsedProfileNameAndIndex()
+	switch {
+	case idx == -2:
+		d.report("default", SeverityWarning, "no profile is set as default", "awsdefault to <profile>")
+	case err != nil:
+		d.report("default", SeverityError,
+			"the default section matches no profile; it was changed manually or the profile was renewed",
+			"awsdefault to <profile>")
+	default:
+		d.report("default", SeverityOK, fmt.Sprintf("the default section matches the profile %s", n), "")
+	}
+}
+
+func (d *doctor) checkExpiry() {
+	ok := true
+	for _, n := range append([]string{"default"}, d.file.GetProfilesNames()...) {
+		p, err := d.file.GetProfileBy(n)
+		if err != nil {
+			continue
+		}
+		if t, found := p.Expiry(); found && t.Before(d.now) && len(p.SessionToken) > 0 {
+			ok = false
+			severity := SeverityWarning
+			if n == "default" {
+				severity = SeverityError
+			}
+			d.report("session_token", severity,
+				fmt.Sprintf("the session token of the profile %s expired at %s", n, t.Format(time.RFC3339)),
+				fmt.Sprintf("renew the temporary credentials of %s and switch to it again", n))
+		}
+	}
+	if ok {
+		d.report("session_token", SeverityOK, "no stale session tokens found", "")
+	}
+}
+
+func (d *doctor) checkConfig() {
+	if d.config == nil {
+		return
+	}
+	ok := true
+	def, err := d.config.GetSection("default")
+	if err == nil {
+		cp := def.HasKey("credential_process")
+		switch mode := d.settings != nil && d.settings.Mode() == ModeCredentialProcess; {
+		case cp && !mode:
+			ok = false
+			d.report("config", SeverityError,
+				"the default profile of the config file uses a credential_process, but awsdefault writes the default section",
+				"awsdefault mode credential_process")
+		case !cp && mode:
+			ok = false
+			d.report("config", SeverityError,
+				"awsdefault is in the credential_process mode, but the config file does not use it",
+				"add 'credential_process = awsdefault credential-process' to the [default] section of "+configPath())
+		}
+		if def.HasKey("aws_access_key_id") {
+			ok = false
+			d.report("config", SeverityWarning,
+				"the default profile of the config file contains keys, which are used whenever the credentials file has none",
+				"remove aws_access_key_id and aws_secret_access_key from the [default] section of "+configPath())
+		}
+		if r := def.Key("region").String(); len(r) > 0 {
+			if p, err := d.file.GetProfileBy("default"); err == nil && len(p.Region) > 0 && p.Region != r {
+				ok = false
+				d.report("config", SeverityWarning,
+					fmt.Sprintf("the AWS cli uses the region %s of the config file instead of %s of the default profile", r, p.Region),
+					"remove the region from the [default] section of "+configPath())
+			}
+		}
+	} else if d.settings != nil && d.settings.Mode() == ModeCredentialProcess {
+		ok = false
+		d.report("config", SeverityError,
+			"awsdefault is in the credential_process mode, but the config file has no default profile",
+			"add 'credential_process = awsdefault credential-process' to the [default] section of "+configPath())
+	}
+	if ok {
+		d.report("config", SeverityOK, "the config file is consistent with the credentials file", "")
+	}
+}
@@ -0,0 +1,124 @@
+//Copyright 2018 Peter Büschel
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package awsdefault
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// environment variables, which would override or redirect the credentials of a profile
+var conflictingEnv = []string{
+	"AWS_PROFILE",
+	"AWS_DEFAULT_PROFILE",
+	"AWS_ACCESS_KEY_ID",
+	"AWS_SECRET_ACCESS_KEY",
+	"AWS_SESSION_TOKEN",
+	"AWS_SECURITY_TOKEN",
+	"AWS_CREDENTIAL_EXPIRATION",
+	"AWS_ROLE_ARN",
+	"AWS_WEB_IDENTITY_TOKEN_FILE",
+	"AWS_CONTAINER_CREDENTIALS_FULL_URI",
+	"AWS_CONTAINER_CREDENTIALS_RELATIVE_URI",
+	"AWS_CONTAINER_AUTHORIZATION_TOKEN",
+}
+
+// Environ returns the given environment, e.g. os.Environ(), with the credentials and the region
+// of the profile. Variables, which would override the credentials, are removed; the region is
+// only replaced, if the profile has one.
+func (p *Profile) Environ(base []string) []string {
+	drop := append([]string{}, conflictingEnv...)
+	if len(p.Region) > 0 {
+		drop = append(drop, "AWS_REGION", "AWS_DEFAULT_REGION")
+	}
+	var env []string
+	for _, e := range base {
+		name := strings.SplitN(e, "=", 2)[0]
+		if !inList(strings.ToUpper(name), drop) {
+			env = append(env, e)
+		}
+	}
+	return append(env, p.Env()...)
+}
+
+func inList(s string, list []string) bool {
+	for _, l := range list {
+		if s == l {
+			return true
+		}
+	}
+	return false
+}
+
+// ResolveCredentials returns the profile with the given name. If the profile contains no keys
+// but a credential_process, the temporary credentials are fetched by running it.
+func ResolveCredentials(s Switcher, name string) (*Profile, error) {
+	p, err := s.GetProfileBy(name)
+	if err != nil {
+		return nil, err
+	}
+	if len(p.AccessKeyID) > 0 {
+		return p, nil
+	}
+	if c := p.keys["credential_process"]; len(c) > 0 {
+		return p.runCredentialProcess(c)
+	}
+	return nil, fmt.Errorf("the profile %s of type %s contains no keys", name, p.Type())
+}
+
+// runCredentialProcess executes the command line and takes over the returned credentials
+func (p *Profile) runCredentialProcess(command string) (*Profile, error) {
+	cmd := exec.Command("/bin/sh", "-c", command)
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	cmd.Env = os.Environ()
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("credential_process failed: %v %s", err, strings.TrimSpace(stderr.String()))
+	}
+	var c ProcessCredentials
+	if err = json.Unmarshal(out, &c); err != nil {
+		return nil, fmt.Errorf("credential_process returned invalid JSON: %v", err)
+	}
+	if c.Version != 1 || len(c.AccessKeyID) == 0 {
+		return nil, fmt.Errorf("credential_process returned no credentials of version 1")
+	}
+	r := &Profile{
+		AccessKeyID:     c.AccessKeyID,
+		SecretAccessKey: Secret(c.SecretAccessKey),
+		SessionToken:    Secret(c.SessionToken),
+		Region:          p.Region,
+		Output:          p.Output,
+		keys:            make(map[string]string),
+	}
+	for k, v := range p.keys {
+		r.keys[k] = v
+	}
+	if len(c.Expiration) > 0 {
+		if _, err := time.Parse(time.RFC3339, c.Expiration); err == nil {
+			r.keys["aws_expiration"] = c.Expiration
+		}
+	}
+	return r, nil
+}
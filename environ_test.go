@@ -0,0 +1,101 @@
+package awsdefault
+
+import (
+	"testing"
+
+	"github.com/go-ini/ini"
+	"github.com/kylelemons/godebug/pretty"
+)
+
+func TestProfile_Environ(t *testing.T) {
+	base := []string{"HOME=/home/user", "AWS_PROFILE=live", "AWS_SESSION_TOKEN=old", "AWS_REGION=us-east-1"}
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{
+			name:    "0positiv - keeps the region of the environment",
+			content: "[p]\naws_access_key_id=A\naws_secret_access_key=B",
+			want: []string{
+				"HOME=/home/user",
+				"AWS_REGION=us-east-1",
+				"AWS_ACCESS_KEY_ID=A",
+				"AWS_SECRET_ACCESS_KEY=B",
+			},
+		},
+		{
+			name:    "1positiv - replaces the region",
+			content: "[p]\naws_access_key_id=A\naws_secret_access_key=B\nregion=eu-west-1",
+			want: []string{
+				"HOME=/home/user",
+				"AWS_ACCESS_KEY_ID=A",
+				"AWS_SECRET_ACCESS_KEY=B",
+				"AWS_REGION=eu-west-1",
+				"AWS_DEFAULT_REGION=eu-west-1",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content, _ := ini.InsensitiveLoad([]byte(tt.content))
+			p, _ := (&CredentialsFile{Content: content}).GetProfileBy("p")
+			if diff := pretty.Compare(tt.want, p.Environ(base)); diff != "" {
+				t.Errorf("Profile.Environ() diff: (-want +got)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestResolveCredentials(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:    "0positiv - static keys",
+			content: "[p]\naws_access_key_id=A\naws_secret_access_key=B",
+			want:    "A",
+		},
+		{
+			name: "1positiv - credential_process",
+			content: "[p]\nregion=eu-west-1\ncredential_process=echo '" +
+				`{"Version":1,"AccessKeyId":"C","SecretAccessKey":"D","SessionToken":"E"}'`,
+			want: "C",
+		},
+		{
+			name:    "2negativ - failing credential_process",
+			content: "[p]\ncredential_process=false",
+			wantErr: true,
+		},
+		{
+			name:    "3negativ - invalid output of the credential_process",
+			content: "[p]\ncredential_process=echo nothing",
+			wantErr: true,
+		},
+		{
+			name:    "4negativ - role without keys",
+			content: "[p]\nrole_arn=arn:aws:iam::123456789012:role/admin",
+			wantErr: true,
+		},
+		{
+			name:    "5negativ - missing profile",
+			content: "[x]\naws_access_key_id=A",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content, _ := ini.InsensitiveLoad([]byte(tt.content))
+			got, err := ResolveCredentials(&CredentialsFile{Content: content}, "p")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ResolveCredentials() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && got.AccessKeyID != tt.want {
+				t.Errorf("ResolveCredentials() = %+v, want %v", got, tt.want)
+			}
+		})
+	}
+}
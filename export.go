@@ -0,0 +1,59 @@
+//Copyright 2018 Peter Büschel
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package awsdefault
+
+import (
+	"time"
+)
+
+// ProcessCredentials is the JSON document expected by the AWS SDKs from a credential_process.
+type ProcessCredentials struct {
+	Version         int    `json:"Version"`
+	AccessKeyID     string `json:"AccessKeyId"`
+	SecretAccessKey string `json:"SecretAccessKey"`
+	SessionToken    string `json:"SessionToken,omitempty"`
+	Expiration      string `json:"Expiration,omitempty"`
+}
+
+// Env returns the environment variables used by the AWS cli and SDKs for the credentials and
+// the region of the profile, in the form "key=value" like os.Environ.
+func (p *Profile) Env() []string {
+	env := []string{
+		"AWS_ACCESS_KEY_ID=" + p.AccessKeyID,
+		"AWS_SECRET_ACCESS_KEY=" + p.SecretAccessKey.Reveal(),
+	}
+	if len(p.SessionToken) > 0 {
+		env = append(env, "AWS_SESSION_TOKEN="+p.SessionToken.Reveal())
+	}
+	if len(p.Region) > 0 {
+		env = append(env, "AWS_REGION="+p.Region, "AWS_DEFAULT_REGION="+p.Region)
+	}
+	return env
+}
+
+// ProcessCredentials returns the credentials of the profile in the format of a
+// credential_process.
+func (p *Profile) ProcessCredentials() ProcessCredentials {
+	c := ProcessCredentials{
+		Version:         1,
+		AccessKeyID:     p.AccessKeyID,
+		SecretAccessKey: p.SecretAccessKey.Reveal(),
+		SessionToken:    p.SessionToken.Reveal(),
+	}
+	if t, ok := p.Expiry(); ok {
+		c.Expiration = t.UTC().Format(time.RFC3339)
+	}
+	return c
+}
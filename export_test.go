@@ -0,0 +1,76 @@
+package awsdefault
+
+import (
+	"testing"
+
+	"github.com/go-ini/ini"
+	"github.com/kylelemons/godebug/pretty"
+)
+
+func TestProfile_Env(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{
+			name:    "0positiv - static keys",
+			content: "[p]\naws_access_key_id=A\naws_secret_access_key=B",
+			want:    []string{"AWS_ACCESS_KEY_ID=A", "AWS_SECRET_ACCESS_KEY=B"},
+		},
+		{
+			name:    "1positiv - temporary keys with region",
+			content: "[p]\naws_access_key_id=A\naws_secret_access_key=B\naws_session_token=C\nregion=eu-west-1",
+			want: []string{
+				"AWS_ACCESS_KEY_ID=A",
+				"AWS_SECRET_ACCESS_KEY=B",
+				"AWS_SESSION_TOKEN=C",
+				"AWS_REGION=eu-west-1",
+				"AWS_DEFAULT_REGION=eu-west-1",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content, _ := ini.InsensitiveLoad([]byte(tt.content))
+			p, _ := (&CredentialsFile{Content: content}).GetProfileBy("p")
+			if diff := pretty.Compare(tt.want, p.Env()); diff != "" {
+				t.Errorf("Profile.Env() diff: (-want +got)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestProfile_ProcessCredentials(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    ProcessCredentials
+	}{
+		{
+			name:    "0positiv - static keys",
+			content: "[p]\naws_access_key_id=A\naws_secret_access_key=B",
+			want:    ProcessCredentials{Version: 1, AccessKeyID: "A", SecretAccessKey: "B"},
+		},
+		{
+			name:    "1positiv - temporary keys with expiry",
+			content: "[p]\naws_access_key_id=A\naws_secret_access_key=B\naws_session_token=C\naws_expiration=2019-03-01T13:00:00+01:00",
+			want: ProcessCredentials{
+				Version:         1,
+				AccessKeyID:     "A",
+				SecretAccessKey: "B",
+				SessionToken:    "C",
+				Expiration:      "2019-03-01T12:00:00Z",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content, _ := ini.InsensitiveLoad([]byte(tt.content))
+			p, _ := (&CredentialsFile{Content: content}).GetProfileBy("p")
+			if diff := pretty.Compare(tt.want, p.ProcessCredentials()); diff != "" {
+				t.Errorf("Profile.ProcessCredentials() diff: (-want +got)\n%s", diff)
+			}
+		})
+	}
+}
@@ -16,6 +16,7 @@ package awsdefault
 
 import (
 	"fmt"
+	"log"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -35,8 +36,8 @@ type (
 	Profile struct {
 		//Name            string `ini:"name"`
 		AccessKeyID     string `ini:"aws_access_key_id"`
-		SecretAccessKey string `ini:"aws_secret_access_key"`
-		SessionToken    string `ini:"aws_session_token,omitempty"`
+		SecretAccessKey Secret `ini:"aws_secret_access_key"`
+		SessionToken    Secret `ini:"aws_session_token,omitempty"`
 		Region          string `ini:"region,omitempty"`
 		Output          string `ini:"output,omitempty"`
 		keys            map[string]string
@@ -52,21 +53,92 @@ type (
 // GetCredentialsFile reads the AWS credentials file either from the HOME directory or
 // from a path given by the environment variable AWS_SHARED_CREDENTIALS_FILE
 func GetCredentialsFile() (*CredentialsFile, error) {
-	home := func() string {
-		if runtime.GOOS == "windows" {
-			return os.Getenv("USERPROFILE")
-		}
-		return os.Getenv("HOME")
+	return loadCredentialsFile(credentialsPath())
+}
+
+// OpenCredentialsFile reads the AWS credentials file like GetCredentialsFile. A missing file is
+// treated as empty; it is created with the first stored profile.
+func OpenCredentialsFile() (*CredentialsFile, error) {
+	ini.DefaultHeader = true
+	path := credentialsPath()
+	f, err := ini.LoadSources(ini.LoadOptions{Insensitive: true, Loose: true}, path)
+	if _, statErr := os.Stat(path); err == nil && statErr == nil {
+		warnPermissions(path)
 	}
-	path := filepath.Join(home(), ".aws", "credentials")
+	return &CredentialsFile{f, path}, err
+}
+
+// credentialsPath returns the path of the AWS credentials file
+func credentialsPath() string {
 	if p := os.Getenv("AWS_SHARED_CREDENTIALS_FILE"); len(p) > 0 {
-		path = p
+		return p
+	}
+	return filepath.Join(homeDir(), ".aws", "credentials")
+}
+
+// configPath returns the path of the AWS config file
+func configPath() string {
+	if p := os.Getenv("AWS_CONFIG_FILE"); len(p) > 0 {
+		return p
+	}
+	return filepath.Join(homeDir(), ".aws", "config")
+}
+
+// homeDir returns the home directory of the current user
+func homeDir() string {
+	if runtime.GOOS == "windows" {
+		return os.Getenv("USERPROFILE")
 	}
+	return os.Getenv("HOME")
+}
+
+// loadCredentialsFile parses the AWS credentials file stored at path. A file accessible by other
+// users is loaded, too, but logs a warning.
+func loadCredentialsFile(path string) (*CredentialsFile, error) {
 	ini.DefaultHeader = true
 	f, err := ini.InsensitiveLoad(path)
+	if err == nil {
+		warnPermissions(path)
+	}
 	return &CredentialsFile{f, path}, err
 }
 
+// update applies the change to the current content of the credentials file and saves it. The
+// file is locked and read again before the change, hence profiles written by other processes since
+// it was loaded, e.g. by the CLI or by 'aws configure', are kept instead of being overwritten with
+// an outdated copy. A file created for the lock is removed again, if the change fails.
+func (f *CredentialsFile) update(change func() error) error {
+	_, missing := os.Stat(f.Path)
+	path, unlock, err := lockPath(f.Path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	content, err := ini.LoadSources(ini.LoadOptions{Insensitive: true, Loose: true}, path)
+	if err != nil {
+		return err
+	}
+	f.Content = content
+	if err = change(); err != nil {
+		if os.IsNotExist(missing) {
+			os.Remove(path)
+		}
+		return err
+	}
+	b, err := encodeIni(f.Content)
+	if err != nil {
+		return err
+	}
+	return replaceFile(path, b)
+}
+
+// warnPermissions logs a warning, if the file is accessible by other users than its owner
+func warnPermissions(path string) {
+	if err := CheckPermissions(path); err != nil {
+		log.Printf("[AWSDEFAULT][WARNING] %v; run 'awsdefault fix-perms'.\n", err)
+	}
+}
+
 // GetProfilesNames returns a sorted list of all available profiles inside the AWS credentials file.
 func (f *CredentialsFile) GetProfilesNames() (names []string) {
 	if f.Content != nil {
@@ -80,13 +152,20 @@ func (f *CredentialsFile) GetProfilesNames() (names []string) {
 	return
 }
 
-// sectionsEqual is helper function to check if two ini sections are equal in one direction
+// sectionsEqual is helper function to check if two ini sections are equal in one direction.
+// The region is ignored, because it can be switched independently of the profile.
 func profilesEqual(s, d *Profile) bool {
-	if len(s.keys) != len(d.keys) {
+	count := func(p *Profile) int {
+		if _, ok := p.keys["region"]; ok {
+			return len(p.keys) - 1
+		}
+		return len(p.keys)
+	}
+	if count(s) != count(d) {
 		return false
 	}
 	for k, v := range s.keys {
-		if d.keys[k] != v {
+		if k != "region" && d.keys[k] != v {
 			return false
 		}
 	}
@@ -129,36 +208,55 @@ func (f *CredentialsFile) GetUsedKey() (string, error) {
 	if len(d.SecretAccessKey) == 0 { // empty default section
 		return "", fmt.Errorf("AWS_SECRET_ACCESS_KEY is not set inside the default section")
 	}
-	return d.SecretAccessKey, nil
+	return d.SecretAccessKey.Reveal(), nil
 }
 
 // GetProfileBy returns the profile by a given name
 func (f *CredentialsFile) GetProfileBy(name string) (*Profile, error) {
-	p := &Profile{keys: make(map[string]string)}
 	s, err := f.Content.GetSection(name)
 	if err != nil {
-		return p, err
+		return &Profile{keys: make(map[string]string)}, err
 	}
+	return profileFrom(s), nil
+}
+
+// profileFrom maps the keys of an ini section onto a new profile
+func profileFrom(s *ini.Section) *Profile {
+	p := &Profile{keys: make(map[string]string)}
 	_ = s.MapTo(p) // error cannot happen; p is always a pointer
 	for _, k := range s.Keys() {
 		p.keys[k.Name()] = k.Value()
 	}
-	return p, nil
+	return p
 }
 
 // SetDefaultTo overwrites/creates the default section inside the AWS credentials file.
-// It also adds a comment containing the name of the profile used as current default profile
+// The region of the default section is either given via "profile@region", the preferred region
+// of the profile or its own region; without any of them the current region is kept.
 func (f *CredentialsFile) SetDefaultTo(profileName string) error {
-	p, err := f.GetProfileBy(profileName)
+	return f.update(func() error { return f.setDefault(profileName) })
+}
+
+// setDefault overwrites/creates the default section like SetDefaultTo without saving the file
+func (f *CredentialsFile) setDefault(profileName string) error {
+	name, p, region, err := f.parseTarget(profileName)
 	if err != nil {
 		return err
 	}
+	if len(region) == 0 {
+		region = preferredRegion(name)
+	}
+	if len(region) > 0 {
+		p.Region = region
+	}
 	_ = f.Content.Section("default").ReflectFrom(p) // error cannot happen; p is always a pointer
-	return f.Content.SaveTo(f.Path)
+	return nil
 }
 
 // UnSetDefault deletes the default section inside the AWS credentials file.
 func (f *CredentialsFile) UnSetDefault() error {
-	f.Content.DeleteSection("default")
-	return f.Content.SaveTo(f.Path)
+	return f.update(func() error {
+		f.Content.DeleteSection("default")
+		return nil
+	})
 }
@@ -42,6 +42,10 @@ func TestCredentialsFile_SetDefaultTo(t *testing.T) {
 	if err != nil {
 		t.Fatalf("CredentialsFile.SetDefaultTo() error = %v", err)
 	}
+	// SetDefaultTo reads the file again before it changes the default section
+	if err = ioutil.WriteFile(testFilePath, testFileContent, 0600); err != nil {
+		t.Fatalf("CredentialsFile.SetDefaultTo() could not write the credentials: %v", err)
+	}
 	type fields struct {
 		Content *ini.File
 		Path    string
@@ -162,6 +166,22 @@ func TestGetCredentialsFile(t *testing.T) {
 	}
 }
 
+func TestOpenCredentialsFile(t *testing.T) {
+	defer os.Unsetenv("AWS_SHARED_CREDENTIALS_FILE")
+	os.Setenv("AWS_SHARED_CREDENTIALS_FILE", "testdata/missing/.aws/credentials")
+	got, err := OpenCredentialsFile()
+	if err != nil {
+		t.Fatalf("OpenCredentialsFile() error = %v", err)
+	}
+	if got.Content == nil || len(got.GetProfilesNames()) > 0 {
+		t.Errorf("OpenCredentialsFile() got profiles %v of a missing file", got.GetProfilesNames())
+	}
+	os.Setenv("AWS_SHARED_CREDENTIALS_FILE", "testdata/malformed/.aws/credentials")
+	if _, err = OpenCredentialsFile(); err == nil {
+		t.Errorf("OpenCredentialsFile() of a malformed file got no error")
+	}
+}
+
 func TestCredentialsFile_GetProfilesNames(t *testing.T) {
 	type fields struct {
 		Content []byte
@@ -357,6 +377,9 @@ aws_secret_access_key = D
 			if err != nil {
 				t.Errorf("CredentialsFile.UnSetDefault() error = %v", err)
 			}
+			if err = ioutil.WriteFile(tt.fields.Path, tt.content, 0600); err != nil {
+				t.Fatalf("CredentialsFile.UnSetDefault() could not write the credentials: %v", err)
+			}
 			f := &CredentialsFile{
 				Content: content,
 				Path:    tt.fields.Path,
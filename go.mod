@@ -1,7 +1,10 @@
 module github.com/peterbueschel/awsdefault
 
+go 1.24
+
 require (
 	github.com/go-ini/ini v1.42.0
+	github.com/godbus/dbus v0.0.0-20181101234600-2ff6f7ffd60f
 	github.com/gotk3/gotk3 v0.0.0-20190227183746-f63906bf28cd
 	github.com/kylelemons/godebug v0.0.0-20170820004349-d65d576e9348
 	github.com/urfave/cli v1.20.0
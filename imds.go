@@ -0,0 +1,160 @@
+//Copyright 2018 Peter Büschel
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package awsdefault
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// paths and headers of the EC2 instance metadata service (IMDSv2)
+const (
+	imdsTokenPath       = "/latest/api/token"
+	imdsCredentialsPath = "/latest/meta-data/iam/security-credentials/"
+	imdsRegionPath      = "/latest/meta-data/placement/region"
+	imdsZonePath        = "/latest/meta-data/placement/availability-zone"
+	imdsIdentityPath    = "/latest/dynamic/instance-identity/document"
+	imdsTokenHeader     = "X-aws-ec2-metadata-token"
+	imdsTTLHeader       = "X-aws-ec2-metadata-token-ttl-seconds"
+	imdsMaxTTL          = 21600
+)
+
+// IMDS emulates the credential related parts of the EC2 instance metadata service in version 2.
+// The credentials of the profile returned by the Source are served as an instance profile with
+// the given Role name; only requests with a valid session token are answered.
+type IMDS struct {
+	Role   string
+	Source ProfileSource
+
+	mu     sync.Mutex
+	tokens map[string]time.Time
+	now    func() time.Time
+}
+
+// NewIMDS returns an IMDS serving the credentials of the source under the given role name.
+func NewIMDS(role string, source ProfileSource) *IMDS {
+	return &IMDS{Role: role, Source: source, tokens: make(map[string]time.Time), now: time.Now}
+}
+
+func (m *IMDS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == imdsTokenPath {
+		m.serveToken(w, r)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !m.valid(r.Header.Get(imdsTokenHeader)) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	switch {
+	case r.URL.Path == imdsCredentialsPath:
+		fmt.Fprint(w, m.Role)
+	case r.URL.Path == imdsCredentialsPath+m.Role:
+		m.serveCredentials(w)
+	case r.URL.Path == imdsRegionPath, r.URL.Path == imdsZonePath, r.URL.Path == imdsIdentityPath:
+		m.serveRegion(w, r.URL.Path)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// serveToken hands out a new session token; like the original service, it refuses requests
+// forwarded by a proxy
+func (m *IMDS) serveToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if len(r.Header.Get("X-Forwarded-For")) > 0 {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+	ttl, err := strconv.Atoi(r.Header.Get(imdsTTLHeader))
+	if err != nil || ttl < 1 || ttl > imdsMaxTTL {
+		http.Error(w, "invalid token TTL", http.StatusBadRequest)
+		return
+	}
+	b := make([]byte, 32)
+	if _, err = rand.Read(b); err != nil {
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	token := hex.EncodeToString(b)
+	m.mu.Lock()
+	now := m.now()
+	for t, expiry := range m.tokens {
+		if now.After(expiry) {
+			delete(m.tokens, t)
+		}
+	}
+	m.tokens[token] = now.Add(time.Duration(ttl) * time.Second)
+	m.mu.Unlock()
+	w.Header().Set(imdsTTLHeader, strconv.Itoa(ttl))
+	fmt.Fprint(w, token)
+}
+
+// valid checks, if the token was handed out and is not expired
+func (m *IMDS) valid(token string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	expiry, ok := m.tokens[token]
+	return ok && !m.now().After(expiry)
+}
+
+func (m *IMDS) serveCredentials(w http.ResponseWriter) {
+	p, err := m.Source()
+	if err != nil {
+		log.Printf("[AWSDEFAULT][ERROR] %v.\n", err)
+		http.Error(w, "no credentials available", http.StatusNotFound)
+		return
+	}
+	now := m.now()
+	c := containerCredentials(p, now)
+	c.Code = "Success"
+	c.LastUpdated = now.UTC().Format(time.RFC3339)
+	c.Type = "AWS-HMAC"
+	writeCredentials(w, c)
+}
+
+// serveRegion answers the region related paths with the region of the profile
+func (m *IMDS) serveRegion(w http.ResponseWriter, path string) {
+	p, err := m.Source()
+	if err != nil || len(p.Region) == 0 {
+		http.Error(w, "no region available", http.StatusNotFound)
+		return
+	}
+	switch {
+	case path == imdsRegionPath:
+		fmt.Fprint(w, p.Region)
+	case path == imdsZonePath:
+		fmt.Fprint(w, p.Region+"a")
+	default: // instance identity document
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"region":           p.Region,
+			"availabilityZone": p.Region + "a",
+		})
+	}
+}
@@ -0,0 +1,154 @@
+package awsdefault
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIMDS(t *testing.T) {
+	now := time.Date(2019, 3, 1, 12, 0, 0, 0, time.UTC)
+	m := NewIMDS("awsdefault", func() (*Profile, error) {
+		return &Profile{AccessKeyID: "A", SecretAccessKey: "B", Region: "eu-central-1"}, nil
+	})
+	m.now = func() time.Time { return now }
+	srv := httptest.NewServer(m)
+	defer srv.Close()
+
+	do := func(method, path string, header map[string]string) (int, string) {
+		req, _ := http.NewRequest(method, srv.URL+path, nil)
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("IMDS request %s %s failed: %s", method, path, err)
+		}
+		defer resp.Body.Close()
+		body, _ := ioutil.ReadAll(resp.Body)
+		return resp.StatusCode, strings.TrimSpace(string(body))
+	}
+
+	code, token := do(http.MethodPut, imdsTokenPath, map[string]string{imdsTTLHeader: "60"})
+	if code != http.StatusOK || len(token) == 0 {
+		t.Fatalf("IMDS PUT token = %v, %v", code, token)
+	}
+	auth := map[string]string{imdsTokenHeader: token}
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		header   map[string]string
+		wantCode int
+		wantBody string
+	}{
+		{
+			name:     "0positiv - list the role",
+			method:   http.MethodGet,
+			path:     imdsCredentialsPath,
+			header:   auth,
+			wantCode: http.StatusOK,
+			wantBody: "awsdefault",
+		},
+		{
+			name:     "1positiv - region of the profile",
+			method:   http.MethodGet,
+			path:     imdsRegionPath,
+			header:   auth,
+			wantCode: http.StatusOK,
+			wantBody: "eu-central-1",
+		},
+		{
+			name:     "2positiv - availability zone",
+			method:   http.MethodGet,
+			path:     imdsZonePath,
+			header:   auth,
+			wantCode: http.StatusOK,
+			wantBody: "eu-central-1a",
+		},
+		{
+			name:     "3negativ - IMDSv1 request without token",
+			method:   http.MethodGet,
+			path:     imdsCredentialsPath,
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:     "4negativ - unknown token",
+			method:   http.MethodGet,
+			path:     imdsCredentialsPath,
+			header:   map[string]string{imdsTokenHeader: "xxxxxxx"},
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:     "5negativ - unknown role",
+			method:   http.MethodGet,
+			path:     imdsCredentialsPath + "xxxxxxx",
+			header:   auth,
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "6negativ - token without TTL",
+			method:   http.MethodPut,
+			path:     imdsTokenPath,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "7negativ - token TTL too long",
+			method:   http.MethodPut,
+			path:     imdsTokenPath,
+			header:   map[string]string{imdsTTLHeader: "21601"},
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "8negativ - token requested via proxy",
+			method:   http.MethodPut,
+			path:     imdsTokenPath,
+			header:   map[string]string{imdsTTLHeader: "60", "X-Forwarded-For": "10.0.0.1"},
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name:     "9negativ - token requested via GET",
+			method:   http.MethodGet,
+			path:     imdsTokenPath,
+			header:   map[string]string{imdsTTLHeader: "60"},
+			wantCode: http.StatusMethodNotAllowed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, body := do(tt.method, tt.path, tt.header)
+			if code != tt.wantCode {
+				t.Errorf("IMDS %s %s code = %v, want %v", tt.method, tt.path, code, tt.wantCode)
+			}
+			if len(tt.wantBody) > 0 && body != tt.wantBody {
+				t.Errorf("IMDS %s %s body = %v, want %v", tt.method, tt.path, body, tt.wantBody)
+			}
+		})
+	}
+
+	t.Run("10positiv - credentials of the role", func(t *testing.T) {
+		code, body := do(http.MethodGet, imdsCredentialsPath+"awsdefault", auth)
+		if code != http.StatusOK {
+			t.Fatalf("IMDS GET credentials code = %v", code)
+		}
+		var c ContainerCredentials
+		if err := json.Unmarshal([]byte(body), &c); err != nil {
+			t.Fatalf("IMDS GET credentials invalid JSON: %v", err)
+		}
+		if c.Code != "Success" || c.Type != "AWS-HMAC" || c.AccessKeyID != "A" || c.SecretAccessKey != "B" {
+			t.Errorf("IMDS GET credentials = %+v", c)
+		}
+	})
+
+	t.Run("11negativ - expired token", func(t *testing.T) {
+		now = now.Add(61 * time.Second)
+		if code, _ := do(http.MethodGet, imdsCredentialsPath, auth); code != http.StatusUnauthorized {
+			t.Errorf("IMDS GET with expired token code = %v", code)
+		}
+	})
+}
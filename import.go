@@ -0,0 +1,141 @@
+//Copyright 2018 Peter Büschel
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package awsdefault
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+var (
+	// ErrProfileExists is returned when a new profile would replace an existing one.
+	ErrProfileExists = errors.New("the profile already exists")
+
+	keyIDPattern  = regexp.MustCompile(`^(AKIA|ASIA)[A-Z0-9]{12,124}$`)
+	secretPattern = regexp.MustCompile(`^[A-Za-z0-9/+]{40}$`)
+)
+
+// ValidateKeys checks the format of an AWS_ACCESS_KEY_ID and an AWS_SECRET_ACCESS_KEY. Temporary
+// keys (starting with ASIA) also require a session token.
+func ValidateKeys(p *Profile) error {
+	if !keyIDPattern.MatchString(p.AccessKeyID) {
+		return fmt.Errorf("%q is no valid AWS_ACCESS_KEY_ID", MaskKeyID(p.AccessKeyID))
+	}
+	if !secretPattern.MatchString(p.SecretAccessKey.Reveal()) {
+		return fmt.Errorf("the AWS_SECRET_ACCESS_KEY has not the format of 40 base64 characters")
+	}
+	if strings.HasPrefix(p.AccessKeyID, "ASIA") && len(p.SessionToken) == 0 {
+		return fmt.Errorf("the temporary AWS_ACCESS_KEY_ID %s requires a session token", MaskKeyID(p.AccessKeyID))
+	}
+	return nil
+}
+
+// ProfilesWithKeyID returns the names of all profiles using the given AWS_ACCESS_KEY_ID.
+func (f *CredentialsFile) ProfilesWithKeyID(id string) []string {
+	var names []string
+	for _, n := range f.GetProfilesNames() {
+		if p, err := f.GetProfileBy(n); err == nil && p.AccessKeyID == id {
+			names = append(names, n)
+		}
+	}
+	return names
+}
+
+// AddProfile stores the profile under the given name. An existing profile is only replaced if
+// overwrite is set; otherwise ErrProfileExists is returned.
+func (f *CredentialsFile) AddProfile(name string, p *Profile, overwrite bool) error {
+	if err := validProfileName(name); err != nil {
+		return err
+	}
+	return f.update(func() error {
+		if _, err := f.Content.GetSection(name); err == nil {
+			if !overwrite {
+				return ErrProfileExists
+			}
+			f.Content.DeleteSection(name)
+		}
+		_ = f.Content.Section(name).ReflectFrom(p) // error cannot happen; p is always a pointer
+		return nil
+	})
+}
+
+// ParseCredentialsCSV reads the keys of the credentials.csv offered by the IAM console for
+// download. Both variants, with and without the name of the user, are supported.
+func ParseCredentialsCSV(r io.Reader) ([]*Profile, error) {
+	rows, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) < 2 {
+		return nil, fmt.Errorf("the CSV file contains no keys")
+	}
+	id, secret := -1, -1
+	for i, h := range rows[0] {
+		switch strings.ToLower(strings.TrimSpace(strings.TrimPrefix(h, "\ufeff"))) {
+		case "access key id":
+			id = i
+		case "secret access key":
+			secret = i
+		}
+	}
+	if id < 0 || secret < 0 {
+		return nil, fmt.Errorf("the CSV file has no columns 'Access key ID' and 'Secret access key'")
+	}
+	var profiles []*Profile
+	for _, row := range rows[1:] {
+		if len(row) <= id || len(row) <= secret {
+			return nil, fmt.Errorf("the CSV file contains an incomplete row")
+		}
+		profiles = append(profiles, &Profile{
+			AccessKeyID:     strings.TrimSpace(row[id]),
+			SecretAccessKey: Secret(strings.TrimSpace(row[secret])),
+			keys:            make(map[string]string),
+		})
+	}
+	return profiles, nil
+}
+
+// ProfileFromEnv reads the credentials and the region from environment variables in the form
+// "key=value" like os.Environ.
+func ProfileFromEnv(environ []string) (*Profile, error) {
+	env := make(map[string]string)
+	for _, e := range environ {
+		kv := strings.SplitN(e, "=", 2)
+		if len(kv) == 2 {
+			env[kv[0]] = kv[1]
+		}
+	}
+	p := &Profile{
+		AccessKeyID:     env["AWS_ACCESS_KEY_ID"],
+		SecretAccessKey: Secret(env["AWS_SECRET_ACCESS_KEY"]),
+		SessionToken:    Secret(env["AWS_SESSION_TOKEN"]),
+		Region:          env["AWS_REGION"],
+		keys:            make(map[string]string),
+	}
+	if len(p.SessionToken) == 0 {
+		p.SessionToken = Secret(env["AWS_SECURITY_TOKEN"])
+	}
+	if len(p.Region) == 0 {
+		p.Region = env["AWS_DEFAULT_REGION"]
+	}
+	if len(p.AccessKeyID) == 0 || len(p.SecretAccessKey) == 0 {
+		return nil, fmt.Errorf("AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY are not set")
+	}
+	return p, nil
+}
@@ -0,0 +1,165 @@
+//Copyright 2018 Peter Büschel
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package awsdefault
+
+import (
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// types of profiles
+const (
+	TypeStatic  = "static"
+	TypeSession = "session"
+	TypeRole    = "role"
+	TypeSSO     = "sso"
+)
+
+// keys used by the AWS cli and other tools to store the expiry of temporary credentials
+var expiryKeys = []string{
+	"aws_expiration", "aws_session_expiration", "expiration", "x_security_token_expires",
+}
+
+// ProfileInfo describes a profile without revealing its secrets. The JSON encoding is the
+// documented and stable schema used by the machine-readable output of the cli tool.
+type ProfileInfo struct {
+	Name   string     `json:"name"`
+	Active bool       `json:"active"`
+	Type   string     `json:"type"`
+	Region string     `json:"region,omitempty"`
+	KeyID  string     `json:"key_id,omitempty"`
+	Expiry *time.Time `json:"expiry,omitempty"`
+}
+
+// Type returns the type of the profile: TypeSSO, TypeRole, TypeSession or TypeStatic.
+func (p *Profile) Type() string {
+	switch {
+	case len(p.keys["sso_start_url"]) > 0 || len(p.keys["sso_session"]) > 0:
+		return TypeSSO
+	case len(p.keys["role_arn"]) > 0:
+		return TypeRole
+	case len(p.SessionToken) > 0:
+		return TypeSession
+	}
+	return TypeStatic
+}
+
+// Expiry returns the expiry of temporary credentials, if it is stored inside the profile.
+func (p *Profile) Expiry() (time.Time, bool) {
+	for _, k := range expiryKeys {
+		if v, ok := p.keys[k]; ok {
+			if t, err := time.Parse(time.RFC3339, v); err == nil {
+				return t, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+var (
+	// ARNs of roles and MFA devices, which carry the account ID
+	arnPattern = regexp.MustCompile(`^arn:aws[a-z-]*:[a-z0-9-]+::([0-9]{12}):`)
+	// long-term and temporary AWS_ACCESS_KEY_IDs, which encode the account ID
+	accountKeyIDPattern = regexp.MustCompile(`^(AKIA|ASIA)[A-Z2-7]{16}$`)
+)
+
+// AccountID returns the ID of the AWS account of the profile: the account of an SSO profile, the
+// account of its role or MFA device, or the account encoded in its AWS_ACCESS_KEY_ID; empty if it
+// is unknown.
+func (p *Profile) AccountID() string {
+	if id := p.keys["sso_account_id"]; len(id) > 0 {
+		return id
+	}
+	for _, k := range []string{"role_arn", "mfa_serial"} {
+		if m := arnPattern.FindStringSubmatch(p.keys[k]); m != nil {
+			return m[1]
+		}
+	}
+	return accountOfKeyID(p.AccessKeyID)
+}
+
+// accountOfKeyID decodes the account ID stored in the bits 7 to 46 of the base32 encoded part
+// of an AWS_ACCESS_KEY_ID
+func accountOfKeyID(id string) string {
+	if !accountKeyIDPattern.MatchString(id) {
+		return ""
+	}
+	b, err := base32.StdEncoding.DecodeString(id[4:])
+	if err != nil {
+		return ""
+	}
+	v := binary.BigEndian.Uint64(append([]byte{0, 0}, b[:6]...))
+	return fmt.Sprintf("%012d", (v&0x7fffffffff80)>>7)
+}
+
+// Countdown returns the time left until the expiry in a short form, e.g. "45m" or "2h05m".
+func Countdown(expiry, now time.Time) string {
+	d := expiry.Sub(now)
+	switch {
+	case d <= 0:
+		return "expired"
+	case d < time.Minute:
+		return "<1m"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", d/time.Minute)
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh%02dm", d/time.Hour, d%time.Hour/time.Minute)
+	}
+	return fmt.Sprintf("%dd", d/(24*time.Hour))
+}
+
+// MaskKeyID hides all but the first and last four characters of an AWS_ACCESS_KEY_ID.
+func MaskKeyID(id string) string {
+	if len(id) <= 8 {
+		return strings.Repeat("*", len(id))
+	}
+	return id[:4] + strings.Repeat("*", len(id)-8) + id[len(id)-4:]
+}
+
+// Info returns the description of the profile stored under the given name.
+func (p *Profile) Info(name string, active bool) ProfileInfo {
+	i := ProfileInfo{
+		Name:   name,
+		Active: active,
+		Type:   p.Type(),
+		Region: p.Region,
+		KeyID:  MaskKeyID(p.AccessKeyID),
+	}
+	if t, ok := p.Expiry(); ok {
+		i.Expiry = &t
+	}
+	return i
+}
+
+// GetProfilesInfo returns the descriptions of all available profiles sorted by name.
+func GetProfilesInfo(s Switcher) ([]ProfileInfo, error) {
+	curr, idx, err := s.GetUsedProfileNameAndIndex()
+	if err != nil || idx < 0 {
+		curr = "" // no default set or default matches no profile
+	}
+	infos := []ProfileInfo{}
+	for _, n := range s.GetProfilesNames() {
+		p, err := s.GetProfileBy(n)
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, p.Info(n, n == curr))
+	}
+	return infos, nil
+}
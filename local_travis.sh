@@ -1,5 +1,5 @@
 #!/usr/bin/env bash
-GO_VERSION=1.9.2
+GO_VERSION=1.24
 
 docker build -f testdata/Dockerfile.travis -t local/travis . && \
 docker run --user root -dit --rm --name travis-debug local/travis:latest /sbin/init
@@ -0,0 +1,124 @@
+//Copyright 2018 Peter Büschel
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package awsdefault
+
+import (
+	"fmt"
+	"strings"
+)
+
+// keys of a profile, which are replaced by UpdateProfile
+var profileKeys = []string{
+	"aws_access_key_id", "aws_secret_access_key", "aws_session_token", "region", "output",
+}
+
+// validProfileName returns an error, if the name cannot be used for a profile
+func validProfileName(name string) error {
+	if len(name) == 0 || strings.ToLower(name) == "default" || strings.ContainsAny(name, "[]") {
+		return fmt.Errorf("%q is no valid name of a profile", name)
+	}
+	return nil
+}
+
+// isDefault reports whether the keys of the given profile are the keys of the default section
+func (f *CredentialsFile) isDefault(name string) bool {
+	n, idx, _ := f.GetUsedProfileNameAndIndex()
+	return idx >= 0 && n == name
+}
+
+// UpdateProfile replaces the keys, the region and the output of an existing profile; all other
+// keys, e.g. role_arn, are kept. The default section follows, if it uses the profile.
+func (f *CredentialsFile) UpdateProfile(name string, p *Profile) error {
+	return f.update(func() error {
+		sec, err := f.Content.GetSection(name)
+		if err != nil || strings.ToLower(name) == "default" {
+			return fmt.Errorf("the profile %s does not exist in %s", name, f.Path)
+		}
+		active := f.isDefault(name)
+		for _, k := range profileKeys {
+			sec.DeleteKey(k)
+		}
+		_ = sec.ReflectFrom(p) // error cannot happen; p is always a pointer
+		if active {
+			return f.setDefault(name)
+		}
+		return nil
+	})
+}
+
+// CopyProfile stores a copy of the profile including its metadata under a new name. An existing
+// profile is never replaced; ErrProfileExists is returned instead.
+func (f *CredentialsFile) CopyProfile(name, newName string) error {
+	return f.copyProfile(name, newName, true)
+}
+
+// RenameProfile moves the profile including its metadata to a new name. A profile selected in
+// the credential_process mode stays selected.
+func (f *CredentialsFile) RenameProfile(name, newName string) error {
+	return f.copyProfile(name, newName, false)
+}
+
+// copyProfile copies the profile and removes the original unless keep is set
+func (f *CredentialsFile) copyProfile(name, newName string, keep bool) error {
+	if err := validProfileName(newName); err != nil {
+		return err
+	}
+	err := f.update(func() error {
+		src, err := f.Content.GetSection(name)
+		if err != nil || strings.ToLower(name) == "default" {
+			return fmt.Errorf("the profile %s does not exist in %s", name, f.Path)
+		}
+		if _, err = f.Content.GetSection(newName); err == nil {
+			return ErrProfileExists
+		}
+		dst := f.Content.Section(newName)
+		for _, k := range src.Keys() {
+			dst.Key(k.Name()).SetValue(k.Value())
+		}
+		if !keep {
+			f.Content.DeleteSection(name)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return moveMetadata(name, newName, keep)
+}
+
+// DeleteProfile removes the profile and its metadata. The default section is left untouched,
+// hence the credentials of a deleted default profile stay in use until the default is changed.
+func (f *CredentialsFile) DeleteProfile(name string) error {
+	err := f.update(func() error {
+		if _, err := f.Content.GetSection(name); err != nil || strings.ToLower(name) == "default" {
+			return fmt.Errorf("the profile %s does not exist in %s", name, f.Path)
+		}
+		f.Content.DeleteSection(name)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return moveMetadata(name, "", false)
+}
+
+// moveMetadata applies Settings.moveMetadata to the settings file
+func moveMetadata(name, newName string, keep bool) error {
+	s, err := GetSettings()
+	if err != nil {
+		return err
+	}
+	return s.moveMetadata(name, newName, keep)
+}
@@ -0,0 +1,215 @@
+//Copyright 2018 Peter Büschel
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package awsdefault
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// profile metadata is stored inside the settings in one section per profile, e.g.
+//
+//	[profile live]
+//	tags = prod, eu
+const profileSectionPrefix = "profile "
+
+func profileSection(name string) string {
+	return profileSectionPrefix + name
+}
+
+// profileValue returns the metadata stored under the key for the given profile
+func (s *Settings) profileValue(name, key string) string {
+	sec, err := s.Content.GetSection(profileSection(name))
+	if err != nil {
+		return ""
+	}
+	return sec.Key(key).String()
+}
+
+// setProfileValue stores the metadata for the given profile; an empty value removes the key
+// and a section without keys gets removed as well.
+func (s *Settings) setProfileValue(name, key, value string) error {
+	sec := s.Content.Section(profileSection(name))
+	if len(value) == 0 {
+		sec.DeleteKey(key)
+	} else {
+		sec.Key(key).SetValue(value)
+	}
+	if len(sec.Keys()) == 0 {
+		s.Content.DeleteSection(sec.Name())
+	}
+	return s.save()
+}
+
+// moveMetadata copies the metadata of a profile to newName and removes the original unless keep
+// is set; an empty newName only removes it. The selection of the credential_process mode follows
+// the profile. Unchanged settings are not written.
+func (s *Settings) moveMetadata(name, newName string, keep bool) error {
+	changed := false
+	if sec, err := s.Content.GetSection(profileSection(name)); err == nil {
+		if len(newName) > 0 {
+			dst := s.Content.Section(profileSection(newName))
+			for _, k := range sec.Keys() {
+				dst.Key(k.Name()).SetValue(k.Value())
+			}
+		}
+		if !keep {
+			s.Content.DeleteSection(sec.Name())
+		}
+		changed = len(newName) > 0 || !keep
+	}
+	if !keep && s.Selected() == name {
+		if len(newName) == 0 {
+			return s.Select("")
+		}
+		s.Content.Section(settingsSection).Key("selected").SetValue(newName)
+		changed = true
+	}
+	if !changed {
+		return nil
+	}
+	return s.save()
+}
+
+// Tags returns the sorted tags of the given profile.
+func (s *Settings) Tags(name string) []string {
+	var tags []string
+	for _, t := range strings.Split(s.profileValue(name, "tags"), ",") {
+		if t = strings.TrimSpace(t); len(t) > 0 {
+			tags = append(tags, t)
+		}
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// SetTags replaces the tags of the given profile; duplicates are removed.
+func (s *Settings) SetTags(name string, tags []string) error {
+	seen := make(map[string]bool)
+	var uniq []string
+	for _, t := range tags {
+		if t = strings.TrimSpace(t); len(t) > 0 && !seen[t] {
+			seen[t] = true
+			uniq = append(uniq, t)
+		}
+	}
+	sort.Strings(uniq)
+	return s.setProfileValue(name, "tags", strings.Join(uniq, ", "))
+}
+
+// AllTags returns every tag used by at least one profile.
+func (s *Settings) AllTags() []string {
+	seen := make(map[string]bool)
+	var tags []string
+	for _, sec := range s.Content.SectionStrings() {
+		if !strings.HasPrefix(sec, profileSectionPrefix) {
+			continue
+		}
+		for _, t := range s.Tags(strings.TrimPrefix(sec, profileSectionPrefix)) {
+			if !seen[t] {
+				seen[t] = true
+				tags = append(tags, t)
+			}
+		}
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// HasTag reports whether the given profile carries the tag.
+func (s *Settings) HasTag(name, tag string) bool {
+	for _, t := range s.Tags(name) {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// environment tags and the colours of their profiles; the first matching tag wins
+var environmentColors = []struct {
+	tags  []string
+	color string
+}{
+	{[]string{"prod", "production", "live"}, "#e01b24"},
+	{[]string{"staging", "stage"}, "#ff7800"},
+	{[]string{"test", "qa"}, "#f6d32d"},
+	{[]string{"dev", "development", "sandbox"}, "#33d17a"},
+}
+
+var colorPattern = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// Color returns the environment colour of the given profile in hex notation: either the colour
+// stored in its metadata or the colour of its environment tag; empty if it has neither.
+func (s *Settings) Color(name string) string {
+	if c := s.profileValue(name, "color"); len(c) > 0 {
+		return c
+	}
+	for _, env := range environmentColors {
+		for _, t := range env.tags {
+			if s.HasTag(name, t) {
+				return env.color
+			}
+		}
+	}
+	return ""
+}
+
+// SetColor stores the colour of the given profile, which overrides the colour of its environment
+// tag; an empty colour removes it.
+func (s *Settings) SetColor(name, color string) error {
+	if len(color) > 0 && !colorPattern.MatchString(color) {
+		return fmt.Errorf("invalid colour %q, want #rgb or #rrggbb", color)
+	}
+	return s.setProfileValue(name, "color", color)
+}
+
+// IsProduction reports whether the given profile is tagged as a production profile.
+func (s *Settings) IsProduction(name string) bool {
+	for _, t := range environmentColors[0].tags {
+		if s.HasTag(name, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// PreferredRegion returns the region used instead of the region of the given profile, when it
+// becomes the default.
+func (s *Settings) PreferredRegion(name string) string {
+	return s.profileValue(name, "region")
+}
+
+// SetPreferredRegion validates and stores the preferred region of the given profile; an empty
+// region removes it.
+func (s *Settings) SetPreferredRegion(name, region string) error {
+	if len(region) > 0 {
+		if _, err := GetRegion(region); err != nil {
+			return err
+		}
+	}
+	return s.setProfileValue(name, "region", region)
+}
+
+// preferredRegion reads the preferred region of the given profile from the settings file
+func preferredRegion(name string) string {
+	s, err := GetSettings()
+	if err != nil {
+		return ""
+	}
+	return s.PreferredRegion(name)
+}
@@ -0,0 +1,83 @@
+package awsdefault
+
+import (
+	"testing"
+
+	"github.com/kylelemons/godebug/pretty"
+)
+
+func TestSettings_Tags(t *testing.T) {
+	_, s, cleanup := testSettings(t)
+	defer cleanup()
+
+	if err := s.SetTags("live", []string{"prod", " eu", "prod", ""}); err != nil {
+		t.Fatalf("Settings.SetTags() error = %v", err)
+	}
+	if err := s.SetTags("dev", []string{"eu"}); err != nil {
+		t.Fatalf("Settings.SetTags() error = %v", err)
+	}
+	got, err := GetSettings()
+	if err != nil {
+		t.Fatalf("GetSettings() error = %v", err)
+	}
+	if diff := pretty.Compare([]string{"eu", "prod"}, got.Tags("live")); diff != "" {
+		t.Errorf("Settings.Tags() diff: (-want +got)\n%s", diff)
+	}
+	if diff := pretty.Compare([]string{"eu", "prod"}, got.AllTags()); diff != "" {
+		t.Errorf("Settings.AllTags() diff: (-want +got)\n%s", diff)
+	}
+	if !got.HasTag("dev", "eu") || got.HasTag("dev", "prod") {
+		t.Errorf("Settings.HasTag() = wrong result for dev")
+	}
+
+	if err = got.SetTags("dev", nil); err != nil {
+		t.Fatalf("Settings.SetTags() error = %v", err)
+	}
+	if _, err = got.Content.GetSection(profileSection("dev")); err == nil {
+		t.Errorf("Settings.SetTags() kept the empty section of dev")
+	}
+}
+
+func TestSettings_PreferredRegion(t *testing.T) {
+	_, s, cleanup := testSettings(t)
+	defer cleanup()
+
+	if err := s.SetPreferredRegion("live", "xx-east-1"); err == nil {
+		t.Errorf("Settings.SetPreferredRegion() no error for an unknown region")
+	}
+	if err := s.SetPreferredRegion("live", "eu-west-1"); err != nil {
+		t.Fatalf("Settings.SetPreferredRegion() error = %v", err)
+	}
+	if r := s.PreferredRegion("live"); r != "eu-west-1" {
+		t.Errorf("Settings.PreferredRegion() = %v, want eu-west-1", r)
+	}
+	if r := preferredRegion("live"); r != "eu-west-1" {
+		t.Errorf("preferredRegion() = %v, want eu-west-1", r)
+	}
+}
+
+func TestSettings_Color(t *testing.T) {
+	_, s, cleanup := testSettings(t)
+	defer cleanup()
+
+	if c := s.Color("live"); c != "" {
+		t.Errorf("Settings.Color() without metadata = %v, want nothing", c)
+	}
+	s.SetTags("live", []string{"eu", "production"})
+	if c := s.Color("live"); c != "#e01b24" || !s.IsProduction("live") {
+		t.Errorf("Settings.Color() = %v, IsProduction() = %v, want #e01b24, true", c, s.IsProduction("live"))
+	}
+	if err := s.SetColor("live", "blue"); err == nil {
+		t.Errorf("Settings.SetColor() no error for an invalid colour")
+	}
+	if err := s.SetColor("live", "#00f"); err != nil {
+		t.Fatalf("Settings.SetColor() error = %v", err)
+	}
+	if c := s.Color("live"); c != "#00f" {
+		t.Errorf("Settings.Color() = %v, want #00f", c)
+	}
+	s.SetTags("dev", []string{"dev"})
+	if c := s.Color("dev"); c != "#33d17a" || s.IsProduction("dev") {
+		t.Errorf("Settings.Color() = %v, IsProduction() = %v, want #33d17a, false", c, s.IsProduction("dev"))
+	}
+}
@@ -0,0 +1,200 @@
+//Copyright 2018 Peter Büschel
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package awsdefault
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+
+	"github.com/go-ini/ini"
+)
+
+// permissions of the files written by awsdefault and of their directories
+const (
+	FileMode os.FileMode = 0600
+	DirMode  os.FileMode = 0700
+)
+
+// PermissionChange describes a file or directory, whose permissions were restricted. Skipped
+// directories are accessible by other users, but only reported with the suggested permissions.
+type PermissionChange struct {
+	Path    string      `json:"path"`
+	Old     os.FileMode `json:"old"`
+	New     os.FileMode `json:"new"`
+	Skipped bool        `json:"skipped,omitempty"`
+}
+
+// saveIni writes the ini file without the unused default section. The header of the default
+// section is only written with ini.DefaultHeader; otherwise the first section is expected to be
+// the default section and its header is omitted, hence it must not be removed.
+func saveIni(f *ini.File, path string) error {
+	content, err := encodeIni(f)
+	if err != nil {
+		return err
+	}
+	return writeSecure(path, content)
+}
+
+// encodeIni returns the content of the ini file as written by saveIni
+func encodeIni(f *ini.File) ([]byte, error) {
+	if d, err := f.GetSection(""); err == nil && len(d.Keys()) == 0 && ini.DefaultHeader {
+		f.DeleteSection(d.Name())
+	}
+	var buf bytes.Buffer
+	if _, err := f.WriteTo(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeSecure replaces the file with the permissions FileMode, also if it already exists with
+// other permissions. The content is written into a temporary file inside the same directory,
+// synced and renamed over the file, while an advisory lock on the file serializes concurrent
+// writers; readers never see a partly written file. A symlink is kept and its target replaced.
+// Missing directories are created with DirMode.
+func writeSecure(path string, content []byte) error {
+	path, unlock, err := lockPath(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return replaceFile(path, content)
+}
+
+// lockPath locks the file at path like writeSecure and returns the path of the locked file, which
+// is the target of a symlink, and the function releasing the lock.
+func lockPath(path string) (string, func(), error) {
+	path = resolveLink(path)
+	if err := os.MkdirAll(filepath.Dir(path), DirMode); err != nil {
+		return "", nil, err
+	}
+	unlock, err := lockFile(path)
+	return path, unlock, err
+}
+
+// replaceFile replaces the file via rename of a temporary file; the caller must hold the lock
+func replaceFile(path string, content []byte) error {
+	dir := filepath.Dir(path)
+	f, err := ioutil.TempFile(dir, "."+filepath.Base(path)+".")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name()) // fails after the rename
+	if err = f.Chmod(FileMode); err != nil {
+		f.Close()
+		return err
+	}
+	if _, err = f.Write(content); err != nil {
+		f.Close()
+		return err
+	}
+	if err = f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// resolveLink follows symlinks to the file they point to, also if that does not exist yet
+func resolveLink(path string) string {
+	for i := 0; i < 40; i++ { // limit of the Linux kernel
+		info, err := os.Lstat(path)
+		if err != nil || info.Mode()&os.ModeSymlink == 0 {
+			return path
+		}
+		target, err := os.Readlink(path)
+		if err != nil {
+			return path
+		}
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(filepath.Dir(path), target)
+		}
+		path = target
+	}
+	return path
+}
+
+// CheckPermissions returns an error, if the file is accessible by other users than its owner.
+// Windows does not support these permissions; there the check always succeeds.
+func CheckPermissions(path string) error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if perm := info.Mode().Perm(); perm&0077 != 0 {
+		return fmt.Errorf("%s is accessible by other users (%04o)", path, perm)
+	}
+	return nil
+}
+
+// FixPermissions restricts the permissions of the credentials file, the AWS config file, the
+// awsdefault settings and of ~/.aws to their owner. Other directories holding these files may be
+// shared, e.g. a mounted volume; they are reported as skipped. Missing files are skipped.
+func FixPermissions() ([]PermissionChange, error) {
+	var changes []PermissionChange
+	restrict := func(path string, mode os.FileMode, skip bool) error {
+		info, err := os.Stat(path)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if old := info.Mode().Perm(); old&^mode != 0 {
+			if !skip {
+				if err = os.Chmod(path, old&mode); err != nil {
+					return err
+				}
+			}
+			changes = append(changes, PermissionChange{Path: path, Old: old, New: old & mode, Skipped: skip})
+		}
+		return nil
+	}
+	if runtime.GOOS == "windows" {
+		return changes, nil
+	}
+	var files []string
+	for _, p := range backupFiles() {
+		files = append(files, p)
+	}
+	sort.Strings(files)
+	aws := filepath.Join(homeDir(), ".aws")
+	dirs := make(map[string]bool)
+	for _, p := range files {
+		if d := filepath.Dir(p); !dirs[d] {
+			dirs[d] = true
+			if err := restrict(d, DirMode, d != aws); err != nil {
+				return changes, err
+			}
+		}
+	}
+	for _, p := range files {
+		if err := restrict(p, FileMode, false); err != nil {
+			return changes, err
+		}
+	}
+	return changes, nil
+}
@@ -0,0 +1,179 @@
+package awsdefault
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/go-ini/ini"
+)
+
+func TestSaveIni(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("permissions are not supported on windows")
+	}
+	dir, err := ioutil.TempDir("", "awsdefault")
+	if err != nil {
+		t.Fatalf("could not create temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	f, _ := ini.InsensitiveLoad([]byte("[live]\naws_access_key_id=A\n"))
+
+	path := filepath.Join(dir, ".aws", "credentials")
+	if err = saveIni(f, path); err != nil {
+		t.Fatalf("saveIni() error = %v", err)
+	}
+	if info, _ := os.Stat(filepath.Dir(path)); info.Mode().Perm() != DirMode {
+		t.Errorf("saveIni() dir mode = %04o, want %04o", info.Mode().Perm(), DirMode)
+	}
+	if err = CheckPermissions(path); err != nil {
+		t.Errorf("CheckPermissions() error = %v", err)
+	}
+
+	os.Chmod(path, 0644)
+	if err = CheckPermissions(path); err == nil {
+		t.Errorf("CheckPermissions() no error for a world-readable file")
+	}
+	if err = saveIni(f, path); err != nil {
+		t.Fatalf("saveIni() error = %v", err)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != FileMode {
+		t.Errorf("saveIni() file mode = %04o, want %04o", info.Mode().Perm(), FileMode)
+	}
+	if err = CheckPermissions(filepath.Join(dir, "missing")); err == nil {
+		t.Errorf("CheckPermissions() no error for a missing file")
+	}
+}
+
+func TestLoadCredentialsFile_permissions(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("permissions are not supported on windows")
+	}
+	dir, err := ioutil.TempDir("", "awsdefault")
+	if err != nil {
+		t.Fatalf("could not create temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	var logged bytes.Buffer
+	log.SetOutput(&logged)
+	defer log.SetOutput(os.Stderr)
+
+	path := filepath.Join(dir, "credentials")
+	for _, perm := range []os.FileMode{FileMode, 0644} {
+		logged.Reset()
+		if err = ioutil.WriteFile(path, []byte("[live]\naws_access_key_id=A\n"), perm); err != nil {
+			t.Fatalf("could not write credentials file: %s", err)
+		}
+		if err = os.Chmod(path, perm); err != nil {
+			t.Fatalf("could not change the mode: %s", err)
+		}
+		if _, err = loadCredentialsFile(path); err != nil {
+			t.Fatalf("loadCredentialsFile() error = %v", err)
+		}
+		if warned := strings.Contains(logged.String(), "accessible by other users"); warned != (perm != FileMode) {
+			t.Errorf("loadCredentialsFile() of a file with mode %04o logged %q", perm, logged.String())
+		}
+		os.Remove(path)
+	}
+}
+
+func TestWriteSecure(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks are not supported on windows")
+	}
+	dir, err := ioutil.TempDir("", "awsdefault")
+	if err != nil {
+		t.Fatalf("could not create temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	path, link := filepath.Join(dir, "credentials"), filepath.Join(dir, "link")
+	if err = os.Symlink(path, link); err != nil {
+		t.Fatalf("could not create symlink: %s", err)
+	}
+
+	// concurrent writers never leave a mixed or partly written file behind
+	contents := make(map[string]bool)
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		c := strings.Repeat(fmt.Sprintf("[p%d]\naws_access_key_id=%d\n", i, i), 1000)
+		contents[c] = true
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := writeSecure(link, []byte(c)); err != nil {
+				t.Errorf("writeSecure() error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	got, err := ioutil.ReadFile(path)
+	if err != nil || !contents[string(got)] {
+		t.Errorf("writeSecure() left %d unexpected bytes, %v", len(got), err)
+	}
+	if info, err := os.Lstat(link); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("writeSecure() replaced the symlink: %v, %v", info, err)
+	}
+	if files, _ := ioutil.ReadDir(dir); len(files) != 2 {
+		t.Errorf("writeSecure() left %d files behind, want the file and the symlink", len(files))
+	}
+}
+
+func TestFixPermissions(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("permissions are not supported on windows")
+	}
+	dir, err := ioutil.TempDir("", "awsdefault")
+	if err != nil {
+		t.Fatalf("could not create temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	defer os.Setenv("HOME", os.Getenv("HOME"))
+	os.Setenv("HOME", dir)
+	aws, shared := filepath.Join(dir, ".aws"), filepath.Join(dir, "shared")
+	os.Mkdir(aws, 0755)
+	os.Mkdir(shared, 0755)
+	for env, path := range map[string]string{
+		"AWS_SHARED_CREDENTIALS_FILE": filepath.Join(aws, "credentials"),
+		"AWS_CONFIG_FILE":             filepath.Join(shared, "config"),
+		"AWSDEFAULT_CONFIG_FILE":      filepath.Join(aws, "awsdefault"),
+	} {
+		os.Setenv(env, path)
+		defer os.Unsetenv(env)
+	}
+	ioutil.WriteFile(filepath.Join(aws, "credentials"), testFileContent, 0644)
+	ioutil.WriteFile(filepath.Join(shared, "config"), []byte("[default]\n"), 0640)
+	ioutil.WriteFile(filepath.Join(aws, "awsdefault"), []byte("[awsdefault]\n"), 0600)
+
+	got, err := FixPermissions()
+	if err != nil {
+		t.Fatalf("FixPermissions() error = %v", err)
+	}
+	skipped := PermissionChange{Path: shared, Old: 0755, New: 0700, Skipped: true}
+	want := []PermissionChange{
+		{Path: aws, Old: 0755, New: 0700},
+		skipped,
+		{Path: filepath.Join(aws, "credentials"), Old: 0644, New: 0600},
+		{Path: filepath.Join(shared, "config"), Old: 0640, New: 0600},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("FixPermissions() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("FixPermissions()[%d] = %v, want %v", i, got[i], want[i])
+		}
+	}
+	if info, _ := os.Stat(shared); info.Mode().Perm() != 0755 {
+		t.Errorf("FixPermissions() changed the shared directory to %04o", info.Mode().Perm())
+	}
+	if got, _ = FixPermissions(); len(got) != 1 || got[0] != skipped {
+		t.Errorf("FixPermissions() second run = %v, want only the skipped directory", got)
+	}
+}
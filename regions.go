@@ -0,0 +1,122 @@
+//Copyright 2018 Peter Büschel
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package awsdefault
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Region is an AWS region together with the partition it belongs to.
+type Region struct {
+	Name        string `json:"name"`
+	Partition   string `json:"partition"`
+	Description string `json:"description"`
+}
+
+// Regions is the bundled list of known AWS regions used to validate the region of a profile.
+var Regions = []Region{
+	{"af-south-1", "aws", "Africa (Cape Town)"},
+	{"ap-east-1", "aws", "Asia Pacific (Hong Kong)"},
+	{"ap-east-2", "aws", "Asia Pacific (Taipei)"},
+	{"ap-northeast-1", "aws", "Asia Pacific (Tokyo)"},
+	{"ap-northeast-2", "aws", "Asia Pacific (Seoul)"},
+	{"ap-northeast-3", "aws", "Asia Pacific (Osaka)"},
+	{"ap-south-1", "aws", "Asia Pacific (Mumbai)"},
+	{"ap-south-2", "aws", "Asia Pacific (Hyderabad)"},
+	{"ap-southeast-1", "aws", "Asia Pacific (Singapore)"},
+	{"ap-southeast-2", "aws", "Asia Pacific (Sydney)"},
+	{"ap-southeast-3", "aws", "Asia Pacific (Jakarta)"},
+	{"ap-southeast-4", "aws", "Asia Pacific (Melbourne)"},
+	{"ap-southeast-5", "aws", "Asia Pacific (Malaysia)"},
+	{"ap-southeast-7", "aws", "Asia Pacific (Thailand)"},
+	{"ca-central-1", "aws", "Canada (Central)"},
+	{"ca-west-1", "aws", "Canada West (Calgary)"},
+	{"eu-central-1", "aws", "Europe (Frankfurt)"},
+	{"eu-central-2", "aws", "Europe (Zurich)"},
+	{"eu-north-1", "aws", "Europe (Stockholm)"},
+	{"eu-south-1", "aws", "Europe (Milan)"},
+	{"eu-south-2", "aws", "Europe (Spain)"},
+	{"eu-west-1", "aws", "Europe (Ireland)"},
+	{"eu-west-2", "aws", "Europe (London)"},
+	{"eu-west-3", "aws", "Europe (Paris)"},
+	{"il-central-1", "aws", "Israel (Tel Aviv)"},
+	{"me-central-1", "aws", "Middle East (UAE)"},
+	{"me-south-1", "aws", "Middle East (Bahrain)"},
+	{"mx-central-1", "aws", "Mexico (Central)"},
+	{"sa-east-1", "aws", "South America (Sao Paulo)"},
+	{"us-east-1", "aws", "US East (N. Virginia)"},
+	{"us-east-2", "aws", "US East (Ohio)"},
+	{"us-west-1", "aws", "US West (N. California)"},
+	{"us-west-2", "aws", "US West (Oregon)"},
+	{"cn-north-1", "aws-cn", "China (Beijing)"},
+	{"cn-northwest-1", "aws-cn", "China (Ningxia)"},
+	{"us-gov-east-1", "aws-us-gov", "AWS GovCloud (US-East)"},
+	{"us-gov-west-1", "aws-us-gov", "AWS GovCloud (US-West)"},
+	{"us-iso-east-1", "aws-iso", "US ISO East"},
+	{"us-iso-west-1", "aws-iso", "US ISO West"},
+	{"us-isob-east-1", "aws-iso-b", "US ISOB East (Ohio)"},
+	{"eu-isoe-west-1", "aws-iso-e", "EU ISOE West"},
+	{"us-isof-east-1", "aws-iso-f", "US ISOF East"},
+	{"us-isof-south-1", "aws-iso-f", "US ISOF South"},
+}
+
+// GetRegion returns the bundled region with the given name.
+func GetRegion(name string) (Region, error) {
+	for _, r := range Regions {
+		if r.Name == name {
+			return r, nil
+		}
+	}
+	return Region{}, fmt.Errorf("unknown region %q", name)
+}
+
+// RegionNames returns the names of all bundled regions.
+func RegionNames() []string {
+	var names []string
+	for _, r := range Regions {
+		names = append(names, r.Name)
+	}
+	return names
+}
+
+// SplitTarget splits the "profile@region" syntax into the name of the profile and the region.
+// The region is empty for a plain profile name.
+func SplitTarget(target string) (string, string) {
+	i := strings.LastIndex(target, "@")
+	if i < 0 {
+		return target, ""
+	}
+	return target[:i], target[i+1:]
+}
+
+// parseTarget returns the profile and the validated region addressed by the target. A profile
+// whose name contains an "@" is still found by its full name.
+func (f *CredentialsFile) parseTarget(target string) (string, *Profile, string, error) {
+	if p, err := f.GetProfileBy(target); err == nil {
+		return target, p, "", nil
+	}
+	name, region := SplitTarget(target)
+	p, err := f.GetProfileBy(name)
+	if err != nil {
+		return "", nil, "", err
+	}
+	if len(region) > 0 {
+		if _, err = GetRegion(region); err != nil {
+			return "", nil, "", err
+		}
+	}
+	return name, p, region, nil
+}
@@ -0,0 +1,120 @@
+package awsdefault
+
+import (
+	"testing"
+)
+
+func TestGetRegion(t *testing.T) {
+	if r, err := GetRegion("cn-north-1"); err != nil || r.Partition != "aws-cn" {
+		t.Errorf("GetRegion() = %+v, %v", r, err)
+	}
+	if _, err := GetRegion("xx-north-1"); err == nil {
+		t.Errorf("GetRegion() no error for an unknown region")
+	}
+}
+
+func TestSplitTarget(t *testing.T) {
+	tests := []struct {
+		target     string
+		wantName   string
+		wantRegion string
+	}{
+		{target: "live", wantName: "live"},
+		{target: "live@eu-central-1", wantName: "live", wantRegion: "eu-central-1"},
+		{target: "me@work@us-east-1", wantName: "me@work", wantRegion: "us-east-1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			n, r := SplitTarget(tt.target)
+			if n != tt.wantName || r != tt.wantRegion {
+				t.Errorf("SplitTarget() = %v, %v, want %v, %v", n, r, tt.wantName, tt.wantRegion)
+			}
+		})
+	}
+}
+
+func TestCredentialsFile_SetDefaultTo_region(t *testing.T) {
+	file, s, cleanup := testSettings(t)
+	defer cleanup()
+
+	tests := []struct {
+		name       string
+		target     string
+		preferred  string
+		wantName   string
+		wantRegion string
+		wantErr    bool
+	}{
+		{
+			name:       "0positiv - profile with region",
+			target:     "live@eu-central-1",
+			wantName:   "live",
+			wantRegion: "eu-central-1",
+		},
+		{
+			name:       "1positiv - current region is kept for a profile without region",
+			target:     "dev",
+			wantName:   "dev",
+			wantRegion: "eu-central-1",
+		},
+		{
+			name:       "2positiv - preferred region",
+			target:     "live",
+			preferred:  "us-west-2",
+			wantName:   "live",
+			wantRegion: "us-west-2",
+		},
+		{
+			name:       "3positiv - given region wins over the preferred one",
+			target:     "live@sa-east-1",
+			preferred:  "us-west-2",
+			wantName:   "live",
+			wantRegion: "sa-east-1",
+		},
+		{
+			name:    "4negativ - unknown region",
+			target:  "live@xx-east-1",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := s.SetPreferredRegion("live", tt.preferred); err != nil {
+				t.Fatalf("Settings.SetPreferredRegion() error = %v", err)
+			}
+			err := file.SetDefaultTo(tt.target)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CredentialsFile.SetDefaultTo() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			n, p, err := GetActiveProfile(file)
+			if err != nil || n != tt.wantName || p.Region != tt.wantRegion {
+				t.Errorf("GetActiveProfile() = %v, %v, %v, want %v, %v", n, p.Region, err, tt.wantName, tt.wantRegion)
+			}
+		})
+	}
+}
+
+func TestSelection_SetDefaultTo_region(t *testing.T) {
+	file, s, cleanup := testSettings(t)
+	defer cleanup()
+	sel := &Selection{CredentialsFile: file, Settings: s}
+
+	if err := s.SwitchMode(file, ModeCredentialProcess); err != nil {
+		t.Fatalf("Settings.SwitchMode() error = %v", err)
+	}
+	if err := sel.SetDefaultTo("live@ap-south-1"); err != nil {
+		t.Fatalf("Selection.SetDefaultTo() error = %v", err)
+	}
+	if n, p, err := GetActiveProfile(sel); err != nil || n != "live" || p.Region != "ap-south-1" {
+		t.Errorf("GetActiveProfile() = %v, %+v, %v", n, p, err)
+	}
+	if err := s.SwitchMode(file, ModeDefault); err != nil {
+		t.Fatalf("Settings.SwitchMode() error = %v", err)
+	}
+	if n, p, err := GetActiveProfile(file); err != nil || n != "live" || p.Region != "ap-south-1" {
+		t.Errorf("GetActiveProfile() after switching the mode = %v, %+v, %v", n, p, err)
+	}
+}
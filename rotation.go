@@ -0,0 +1,75 @@
+//Copyright 2018 Peter Büschel
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package awsdefault
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RotationOf returns the profiles to cycle through: the rotation of the settings without the names
+// of missing profiles or, if none is configured, all profiles in the order of the switcher. The
+// names of the rotation are compared case-insensitive like the sections of the credentials file.
+// With none, the empty name standing for no default profile completes the rotation.
+func RotationOf(s Switcher, settings *Settings, none bool) []string {
+	names := s.GetProfilesNames()
+	var rotation []string
+	if settings != nil {
+		profiles := make(map[string]string)
+		for _, n := range names {
+			profiles[strings.ToLower(n)] = n
+		}
+		for _, n := range settings.Rotation() {
+			if p, ok := profiles[strings.ToLower(n)]; ok {
+				rotation = append(rotation, p)
+			}
+		}
+	}
+	if len(rotation) == 0 {
+		rotation = append(rotation, names...)
+	}
+	if none {
+		rotation = append(rotation, "")
+	}
+	return rotation
+}
+
+// Cycle returns the profile following the current one inside the rotation or, with backward, the
+// one before it; the rotation wraps around. A current profile outside the rotation starts at its
+// beginning or, with backward, at its end. Profiles rejected by accept are passed over; nil
+// accepts all of them.
+func Cycle(rotation []string, curr string, backward bool, accept func(name string) bool) (string, error) {
+	n := len(rotation)
+	step, start := 1, -1
+	if backward {
+		step, start = -1, n
+	}
+	for i, r := range rotation {
+		if r == curr {
+			start = i
+			break
+		}
+	}
+	for i := 1; i <= n; i++ {
+		name := rotation[((start+i*step)%n+n)%n]
+		if name == curr {
+			break // back at the current profile
+		}
+		if accept == nil || accept(name) {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("no other profile to switch to in the rotation")
+}
@@ -0,0 +1,60 @@
+package awsdefault
+
+import (
+	"testing"
+
+	"github.com/go-ini/ini"
+	"github.com/kylelemons/godebug/pretty"
+)
+
+func TestRotationOf(t *testing.T) {
+	content, _ := ini.InsensitiveLoad([]byte("[dev]\n[test]\n[live]\n"))
+	file := &CredentialsFile{Content: content}
+	settings := &Settings{Content: ini.Empty()}
+	if diff := pretty.Compare(RotationOf(file, settings, false), file.GetProfilesNames()); diff != "" {
+		t.Errorf("RotationOf() without configuration diff: (-got +want)\n%s", diff)
+	}
+	settings.Content.Section(settingsSection).Key("rotation").SetValue("Live, gone, dev")
+	if diff := pretty.Compare(RotationOf(file, settings, true), []string{"live", "dev", ""}); diff != "" {
+		t.Errorf("RotationOf() diff: (-got +want)\n%s", diff)
+	}
+	settings.Content.Section(settingsSection).Key("rotation_none").SetValue("true")
+	if !settings.RotationWithNone() {
+		t.Errorf("Settings.RotationWithNone() = false, want true")
+	}
+}
+
+func TestCycle(t *testing.T) {
+	rotation := []string{"dev", "test", "live", ""}
+	protected := func(name string) bool { return name != "live" }
+	tests := []struct {
+		name     string
+		rotation []string
+		curr     string
+		backward bool
+		accept   func(string) bool
+		want     string
+		wantErr  bool
+	}{
+		{name: "0positiv - next", rotation: rotation, curr: "dev", want: "test"},
+		{name: "1positiv - prev", rotation: rotation, curr: "test", backward: true, want: "dev"},
+		{name: "2positiv - next wraps around", rotation: rotation, curr: "", want: "dev"},
+		{name: "3positiv - prev wraps around", rotation: rotation, curr: "dev", backward: true, want: ""},
+		{name: "4positiv - protected profile is skipped", rotation: rotation, curr: "test", accept: protected, want: ""},
+		{name: "5positiv - outside the rotation next", rotation: rotation[:3], curr: "", want: "dev"},
+		{name: "6positiv - outside the rotation prev", rotation: rotation[:3], curr: "", backward: true, want: "live"},
+		{name: "7negativ - nothing else to switch to", rotation: []string{"dev", "live"}, curr: "dev", accept: protected, wantErr: true},
+		{name: "8negativ - empty rotation", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Cycle(tt.rotation, tt.curr, tt.backward, tt.accept)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Cycle() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Cycle() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
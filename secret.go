@@ -0,0 +1,78 @@
+//Copyright 2018 Peter Büschel
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package awsdefault
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// mask replaces all but the last characters of a secret
+const mask = "********"
+
+// Secret is a secret access key or a session token. It is redacted whenever it is formatted or
+// marshalled; only Reveal returns the value itself.
+type Secret string
+
+// Reveal returns the secret in plain text.
+func (s Secret) Reveal() string {
+	return string(s)
+}
+
+// String returns the masked secret, see MaskSecret.
+func (s Secret) String() string {
+	return MaskSecret(string(s))
+}
+
+// GoString returns the masked secret for the %#v verb.
+func (s Secret) GoString() string {
+	return strconv.Quote(s.String())
+}
+
+// MarshalJSON returns the masked secret as JSON string.
+func (s Secret) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+// MarshalText returns the masked secret, e.g. for encoding/xml.
+func (s Secret) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// MaskSecret hides a secret except for its last four characters, which are enough to tell
+// secrets apart. Short secrets are hidden completely.
+func MaskSecret(s string) string {
+	switch {
+	case len(s) == 0:
+		return ""
+	case len(s) < 16:
+		return mask
+	}
+	return mask + s[len(s)-4:]
+}
+
+// String formats the profile without revealing its secrets.
+func (p Profile) String() string {
+	return fmt.Sprintf("{%s %s %s %s %s}", p.AccessKeyID, p.SecretAccessKey, p.SessionToken, p.Region, p.Output)
+}
+
+// GoString formats the profile for the %#v verb without revealing its secrets.
+func (p Profile) GoString() string {
+	return fmt.Sprintf(
+		"awsdefault.Profile{AccessKeyID:%q, SecretAccessKey:%#v, SessionToken:%#v, Region:%q, Output:%q}",
+		p.AccessKeyID, p.SecretAccessKey, p.SessionToken, p.Region, p.Output,
+	)
+}
@@ -0,0 +1,98 @@
+//Copyright 2018 Peter Büschel
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package awsdefault
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+)
+
+// CredentialsLifetime is the maximal lifetime announced for served credentials. A short lifetime
+// lets the SDKs ask again soon, so they pick up a switched profile.
+var CredentialsLifetime = 15 * time.Minute
+
+type (
+	// ProfileSource returns the profile, whose credentials get served.
+	ProfileSource func() (*Profile, error)
+
+	// ContainerCredentials is the JSON document of the ECS container credentials endpoint and,
+	// together with the fields Code, LastUpdated and Type, of the EC2 instance metadata service.
+	ContainerCredentials struct {
+		Code            string `json:"Code,omitempty"`
+		LastUpdated     string `json:"LastUpdated,omitempty"`
+		Type            string `json:"Type,omitempty"`
+		AccessKeyID     string `json:"AccessKeyId"`
+		SecretAccessKey string `json:"SecretAccessKey"`
+		Token           string `json:"Token,omitempty"`
+		Expiration      string `json:"Expiration"`
+	}
+)
+
+// LoadActiveProfile reads the credentials file and returns the currently used profile. It is
+// the ProfileSource of the served endpoints, which always follow the latest switch.
+func LoadActiveProfile() (*Profile, error) {
+	file, err := GetCredentialsFile()
+	if err != nil {
+		return nil, err
+	}
+	_, p, err := GetActiveProfile(LocalSwitcher(file))
+	return p, err
+}
+
+// containerCredentials converts the profile into the served JSON document
+func containerCredentials(p *Profile, now time.Time) ContainerCredentials {
+	expiry := now.Add(CredentialsLifetime)
+	if t, ok := p.Expiry(); ok && t.Before(expiry) {
+		expiry = t
+	}
+	return ContainerCredentials{
+		AccessKeyID:     p.AccessKeyID,
+		SecretAccessKey: p.SecretAccessKey.Reveal(),
+		Token:           p.SessionToken.Reveal(),
+		Expiration:      expiry.UTC().Format(time.RFC3339),
+	}
+}
+
+// NewContainerCredentialsHandler returns a handler implementing the ECS container credentials
+// protocol. Requests must carry the token inside the Authorization header, as sent by the SDKs
+// configured via AWS_CONTAINER_CREDENTIALS_FULL_URI and AWS_CONTAINER_AUTHORIZATION_TOKEN.
+func NewContainerCredentialsHandler(token string, source ProfileSource) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		auth := r.Header.Get("Authorization")
+		if subtle.ConstantTimeCompare([]byte(auth), []byte(token)) != 1 {
+			http.Error(w, "invalid authorization token", http.StatusUnauthorized)
+			return
+		}
+		p, err := source()
+		if err != nil {
+			log.Printf("[AWSDEFAULT][ERROR] %v.\n", err)
+			http.Error(w, "no credentials available", http.StatusNotFound)
+			return
+		}
+		writeCredentials(w, containerCredentials(p, time.Now()))
+	})
+}
+
+func writeCredentials(w http.ResponseWriter, c ContainerCredentials) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(c)
+}
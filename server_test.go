@@ -0,0 +1,112 @@
+package awsdefault
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kylelemons/godebug/pretty"
+)
+
+func TestNewContainerCredentialsHandler(t *testing.T) {
+	CredentialsLifetime = time.Hour
+	profile := &Profile{AccessKeyID: "A", SecretAccessKey: "B", SessionToken: "C"}
+	source := func() (*Profile, error) { return profile, nil }
+	failing := func() (*Profile, error) { return nil, fmt.Errorf("no profile") }
+	tests := []struct {
+		name     string
+		method   string
+		token    string
+		source   ProfileSource
+		wantCode int
+		want     ContainerCredentials
+	}{
+		{
+			name:     "0positiv - credentials of the active profile",
+			method:   http.MethodGet,
+			token:    "secret",
+			source:   source,
+			wantCode: http.StatusOK,
+			want:     ContainerCredentials{AccessKeyID: "A", SecretAccessKey: "B", Token: "C"},
+		},
+		{
+			name:     "1negativ - wrong token",
+			method:   http.MethodGet,
+			token:    "xxxxxxx",
+			source:   source,
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:     "2negativ - wrong method",
+			method:   http.MethodPost,
+			token:    "secret",
+			source:   source,
+			wantCode: http.StatusMethodNotAllowed,
+		},
+		{
+			name:     "3negativ - no active profile",
+			method:   http.MethodGet,
+			token:    "secret",
+			source:   failing,
+			wantCode: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, "/credentials", nil)
+			r.Header.Set("Authorization", tt.token)
+			w := httptest.NewRecorder()
+			NewContainerCredentialsHandler("secret", tt.source).ServeHTTP(w, r)
+			if w.Code != tt.wantCode {
+				t.Errorf("NewContainerCredentialsHandler() code = %v, want %v", w.Code, tt.wantCode)
+				return
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+			var got ContainerCredentials
+			if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+				t.Fatalf("NewContainerCredentialsHandler() invalid JSON: %v", err)
+			}
+			expiry, err := time.Parse(time.RFC3339, got.Expiration)
+			if err != nil || time.Until(expiry) < 50*time.Minute {
+				t.Errorf("NewContainerCredentialsHandler() expiration = %v", got.Expiration)
+			}
+			got.Expiration = ""
+			if diff := pretty.Compare(tt.want, got); diff != "" {
+				t.Errorf("NewContainerCredentialsHandler() diff: (-want +got)\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_containerCredentials(t *testing.T) {
+	CredentialsLifetime = 15 * time.Minute
+	now := time.Date(2019, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		profile *Profile
+		want    string
+	}{
+		{
+			name:    "0positiv - static keys expire after the lifetime",
+			profile: &Profile{},
+			want:    "2019-03-01T12:15:00Z",
+		},
+		{
+			name:    "1positiv - temporary keys expire earlier",
+			profile: &Profile{keys: map[string]string{"aws_expiration": "2019-03-01T12:05:00Z"}},
+			want:    "2019-03-01T12:05:00Z",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := containerCredentials(tt.profile, now).Expiration; got != tt.want {
+				t.Errorf("containerCredentials() expiration = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,294 @@
+//Copyright 2018 Peter Büschel
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package awsdefault
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/go-ini/ini"
+)
+
+// modes of awsdefault
+const (
+	// ModeDefault copies the keys of the chosen profile into the default section.
+	ModeDefault = "default"
+	// ModeCredentialProcess only records the chosen profile; the AWS SDKs ask
+	// `awsdefault credential-process` for its keys.
+	ModeCredentialProcess = "credential_process"
+)
+
+// criteria to group the profiles by
+const (
+	GroupByTag     = "tag"
+	GroupByAccount = "account"
+	GroupByPrefix  = "prefix"
+)
+
+// DefaultGroupPrefix groups profiles named <team>-<env> by their team.
+const DefaultGroupPrefix = `^([^-]+)-`
+
+const settingsSection = "awsdefault"
+
+type (
+	// Settings stores the configuration and the state of awsdefault itself in an ini file next to
+	// the AWS credentials file.
+	Settings struct {
+		Content *ini.File
+		Path    string
+	}
+
+	// Selection is the Switcher of the credential_process mode. Instead of writing the keys of
+	// the chosen profile into the default section, it records the name inside the Settings.
+	Selection struct {
+		*CredentialsFile
+		Settings *Settings
+	}
+)
+
+// GetSettings reads the awsdefault settings either from $HOME/.aws/awsdefault or from a path
+// given by the environment variable AWSDEFAULT_CONFIG_FILE. A missing file results in empty
+// settings.
+func GetSettings() (*Settings, error) {
+	path := settingsPath()
+	f, err := ini.LoadSources(ini.LoadOptions{Insensitive: true, Loose: true}, path)
+	return &Settings{f, path}, err
+}
+
+// settingsPath returns the path of the awsdefault settings file
+func settingsPath() string {
+	if p := os.Getenv("AWSDEFAULT_CONFIG_FILE"); len(p) > 0 {
+		return p
+	}
+	return filepath.Join(homeDir(), ".aws", "awsdefault")
+}
+
+// Mode returns the configured mode; ModeDefault if nothing is configured.
+func (s *Settings) Mode() string {
+	if m := s.Content.Section(settingsSection).Key("mode").String(); m == ModeCredentialProcess {
+		return m
+	}
+	return ModeDefault
+}
+
+// Selected returns the name of the profile chosen in the credential_process mode.
+func (s *Settings) Selected() string {
+	return s.Content.Section(settingsSection).Key("selected").String()
+}
+
+// Region returns the region chosen together with the profile in the credential_process mode.
+func (s *Settings) Region() string {
+	return s.Content.Section(settingsSection).Key("region").String()
+}
+
+// STSEndpoint returns the configured endpoint of the AWS Security Token Service used to obtain
+// session credentials for profiles with MFA; DefaultSTSEndpoint if nothing is configured.
+func (s *Settings) STSEndpoint() string {
+	if e := s.Content.Section(settingsSection).Key("sts_endpoint").String(); len(e) > 0 {
+		return e
+	}
+	return DefaultSTSEndpoint
+}
+
+// GroupBy returns the configured criterion to group the profiles by: GroupByTag, GroupByAccount,
+// GroupByPrefix or empty for an ungrouped list.
+func (s *Settings) GroupBy() string {
+	return s.Content.Section(settingsSection).Key("group_by").String()
+}
+
+// GroupPrefix returns the rule grouping the profiles by a prefix of their names: the first
+// capture group or, without one, the whole match is the group; DefaultGroupPrefix if nothing is
+// configured.
+func (s *Settings) GroupPrefix() (*regexp.Regexp, error) {
+	rule := s.Content.Section(settingsSection).Key("group_prefix").String()
+	if len(rule) == 0 {
+		rule = DefaultGroupPrefix
+	}
+	re, err := regexp.Compile(rule)
+	if err != nil {
+		return nil, fmt.Errorf("invalid group_prefix %q: %s", rule, err)
+	}
+	return re, nil
+}
+
+// Rotation returns the configured order of the profiles to cycle through with next and prev;
+// empty, if the order of the credentials file is used.
+func (s *Settings) Rotation() []string {
+	var names []string
+	for _, n := range strings.Split(s.Content.Section(settingsSection).Key("rotation").String(), ",") {
+		if n = strings.TrimSpace(n); len(n) > 0 {
+			names = append(names, n)
+		}
+	}
+	return names
+}
+
+// RotationWithNone reports whether cycling through the profiles includes the state without a
+// default profile.
+func (s *Settings) RotationWithNone() bool {
+	return s.Content.Section(settingsSection).Key("rotation_none").MustBool(false)
+}
+
+// Select records the name of the chosen profile; an empty name removes the selection.
+func (s *Settings) Select(name string) error {
+	return s.SelectWithRegion(name, "")
+}
+
+// SelectWithRegion records the name of the chosen profile together with a region, which
+// overrides the region of the profile; empty values remove them.
+func (s *Settings) SelectWithRegion(name, region string) error {
+	sec := s.Content.Section(settingsSection)
+	for k, v := range map[string]string{"selected": name, "region": region} {
+		if len(v) == 0 || len(name) == 0 {
+			sec.DeleteKey(k)
+		} else {
+			sec.Key(k).SetValue(v)
+		}
+	}
+	return s.save()
+}
+
+// SwitchMode changes the mode and moves the currently used profile over: entering the
+// credential_process mode records the default profile and removes the default section, leaving
+// it restores the default section from the selection.
+func (s *Settings) SwitchMode(file *CredentialsFile, mode string) error {
+	if mode != ModeDefault && mode != ModeCredentialProcess {
+		return fmt.Errorf("unknown mode %q", mode)
+	}
+	if mode == s.Mode() {
+		return nil
+	}
+	sec := s.Content.Section(settingsSection)
+	if mode == ModeCredentialProcess {
+		if n, idx, _ := file.GetUsedProfileNameAndIndex(); idx >= 0 {
+			sec.Key("selected").SetValue(n)
+			if d, _ := file.GetProfileBy("default"); len(d.Region) > 0 {
+				sec.Key("region").SetValue(d.Region)
+			}
+		}
+		if err := file.UnSetDefault(); err != nil {
+			return err
+		}
+	} else if n := s.Selected(); len(n) > 0 {
+		if r := s.Region(); len(r) > 0 {
+			n += "@" + r
+		}
+		if err := file.SetDefaultTo(n); err != nil {
+			return err
+		}
+		sec.DeleteKey("selected")
+		sec.DeleteKey("region")
+	}
+	sec.Key("mode").SetValue(mode)
+	return s.save()
+}
+
+// save writes the settings
+func (s *Settings) save() error {
+	return saveIni(s.Content, s.Path)
+}
+
+// LocalSwitcher returns the Switcher working directly on the credentials file, depending on the
+// configured mode either the CredentialsFile itself or a Selection.
+func LocalSwitcher(file *CredentialsFile) Switcher {
+	s, err := GetSettings()
+	if err != nil || s.Mode() != ModeCredentialProcess {
+		return file
+	}
+	return &Selection{CredentialsFile: file, Settings: s}
+}
+
+// GetUsedProfileNameAndIndex returns the name and the index of the selected profile.
+func (s *Selection) GetUsedProfileNameAndIndex() (string, int, error) {
+	n := s.Settings.Selected()
+	if len(n) == 0 {
+		return "no default", -2, nil
+	}
+	for idx, p := range s.GetProfilesNames() {
+		if p == n {
+			return n, idx, nil
+		}
+	}
+	return "", -1, fmt.Errorf("the selected profile %s does not exist in %s", n, s.Path)
+}
+
+// GetProfileBy returns the profile by a given name. The name "default" returns the selected
+// profile with the selected region, like the default section in the default mode.
+func (s *Selection) GetProfileBy(name string) (*Profile, error) {
+	if name != "default" {
+		return s.CredentialsFile.GetProfileBy(name)
+	}
+	n, idx, err := s.GetUsedProfileNameAndIndex()
+	if idx < 0 {
+		if err == nil {
+			err = fmt.Errorf("no profile is selected")
+		}
+		return &Profile{keys: make(map[string]string)}, err
+	}
+	p, err := s.CredentialsFile.GetProfileBy(n)
+	if r := s.Settings.Region(); len(r) > 0 {
+		p.Region = r
+	}
+	return p, err
+}
+
+// SetDefaultTo records the given profile as the selected one. Like in the default mode, the
+// region is either given via "profile@region" or the preferred region of the profile.
+func (s *Selection) SetDefaultTo(profileName string) error {
+	name, _, region, err := s.parseTarget(profileName)
+	if err != nil {
+		return err
+	}
+	if len(region) == 0 {
+		region = s.Settings.PreferredRegion(name)
+	}
+	return s.Settings.SelectWithRegion(name, region)
+}
+
+// UnSetDefault removes the selection.
+func (s *Selection) UnSetDefault() error {
+	return s.Settings.Select("")
+}
+
+// GetActiveProfile returns the name and the profile, whose credentials are currently used.
+func GetActiveProfile(s Switcher) (string, *Profile, error) {
+	n, idx, err := s.GetUsedProfileNameAndIndex()
+	switch {
+	case idx == -2:
+		return "", nil, fmt.Errorf("no profile is set as default")
+	case err == nil:
+		p, err := s.GetProfileBy(n)
+		if err != nil {
+			return n, p, err
+		}
+		// the region can be switched independently of the profile
+		if d, derr := s.GetProfileBy("default"); derr == nil && len(d.Region) > 0 {
+			p.Region = d.Region
+		}
+		return n, p, nil
+	}
+	if _, ok := s.(*Selection); ok {
+		return "", nil, err
+	}
+	// the default section was changed manually and matches no profile; it is in use anyway
+	p, derr := s.GetProfileBy("default")
+	if derr != nil {
+		return "", nil, err
+	}
+	return "default", p, nil
+}
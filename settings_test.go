@@ -0,0 +1,188 @@
+package awsdefault
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-ini/ini"
+)
+
+// testSettings writes a copy of the test credentials file and returns it together with empty
+// settings inside a temporary directory
+func testSettings(t *testing.T) (*CredentialsFile, *Settings, func()) {
+	dir, err := ioutil.TempDir("", "awsdefault")
+	if err != nil {
+		t.Fatalf("could not create temp dir: %s", err)
+	}
+	path := filepath.Join(dir, "credentials")
+	if err = ioutil.WriteFile(path, testFileContent, 0600); err != nil {
+		t.Fatalf("could not write credentials file: %s", err)
+	}
+	file, err := loadCredentialsFile(path)
+	if err != nil {
+		t.Fatalf("could not load credentials file: %s", err)
+	}
+	os.Setenv("AWSDEFAULT_CONFIG_FILE", filepath.Join(dir, "awsdefault"))
+	s, err := GetSettings()
+	if err != nil {
+		t.Fatalf("could not load settings: %s", err)
+	}
+	return file, s, func() {
+		os.Unsetenv("AWSDEFAULT_CONFIG_FILE")
+		os.RemoveAll(dir)
+	}
+}
+
+func TestGetSettings(t *testing.T) {
+	_, s, cleanup := testSettings(t)
+	defer cleanup()
+
+	if m := s.Mode(); m != ModeDefault {
+		t.Errorf("Settings.Mode() of missing file = %v, want %v", m, ModeDefault)
+	}
+	if e := s.STSEndpoint(); e != DefaultSTSEndpoint {
+		t.Errorf("Settings.STSEndpoint() of missing file = %v, want %v", e, DefaultSTSEndpoint)
+	}
+	if g := s.GroupBy(); g != "" {
+		t.Errorf("Settings.GroupBy() of missing file = %v, want nothing", g)
+	}
+	if re, err := s.GroupPrefix(); err != nil || re.String() != DefaultGroupPrefix {
+		t.Errorf("Settings.GroupPrefix() of missing file = %v, %v, want %v", re, err, DefaultGroupPrefix)
+	}
+	s.Content.Section(settingsSection).Key("group_prefix").SetValue("([")
+	if _, err := s.GroupPrefix(); err == nil {
+		t.Errorf("Settings.GroupPrefix() of an invalid rule, want error")
+	}
+	s.Content.Section(settingsSection).DeleteKey("group_prefix")
+	if err := s.Select("live"); err != nil {
+		t.Fatalf("Settings.Select() error = %v", err)
+	}
+	got, err := GetSettings()
+	if err != nil {
+		t.Fatalf("GetSettings() error = %v", err)
+	}
+	if n := got.Selected(); n != "live" {
+		t.Errorf("Settings.Selected() = %v, want live", n)
+	}
+	if err := got.Select(""); err != nil {
+		t.Fatalf("Settings.Select() error = %v", err)
+	}
+	if n := got.Selected(); n != "" {
+		t.Errorf("Settings.Selected() = %v, want nothing", n)
+	}
+}
+
+func TestSettings_SwitchMode(t *testing.T) {
+	file, s, cleanup := testSettings(t)
+	defer cleanup()
+
+	if err := s.SwitchMode(file, "xxxxxxx"); err == nil {
+		t.Errorf("Settings.SwitchMode() no error for an unknown mode")
+	}
+	if err := s.SwitchMode(file, ModeCredentialProcess); err != nil {
+		t.Fatalf("Settings.SwitchMode() error = %v", err)
+	}
+	if s.Mode() != ModeCredentialProcess || s.Selected() != "dev" {
+		t.Errorf("Settings.SwitchMode() mode = %v, selected = %v", s.Mode(), s.Selected())
+	}
+	if _, err := file.Content.GetSection("default"); err == nil {
+		t.Errorf("Settings.SwitchMode() default section still exists")
+	}
+	if _, ok := LocalSwitcher(file).(*Selection); !ok {
+		t.Errorf("LocalSwitcher() is not a selection in the credential_process mode")
+	}
+
+	s.Select("live")
+	if err := s.SwitchMode(file, ModeDefault); err != nil {
+		t.Fatalf("Settings.SwitchMode() error = %v", err)
+	}
+	if n, _, _ := file.GetUsedProfileNameAndIndex(); n != "live" {
+		t.Errorf("Settings.SwitchMode() default profile = %v, want live", n)
+	}
+	if _, ok := LocalSwitcher(file).(*CredentialsFile); !ok {
+		t.Errorf("LocalSwitcher() is not the credentials file in the default mode")
+	}
+}
+
+func TestSelection(t *testing.T) {
+	file, s, cleanup := testSettings(t)
+	defer cleanup()
+	sel := &Selection{CredentialsFile: file, Settings: s}
+
+	if _, idx, err := sel.GetUsedProfileNameAndIndex(); idx != -2 || err != nil {
+		t.Errorf("Selection.GetUsedProfileNameAndIndex() = %v, %v, want no default", idx, err)
+	}
+	if err := sel.SetDefaultTo("xxxxxxx"); err == nil {
+		t.Errorf("Selection.SetDefaultTo() no error for a missing profile")
+	}
+	if err := sel.SetDefaultTo("live"); err != nil {
+		t.Fatalf("Selection.SetDefaultTo() error = %v", err)
+	}
+	if n, idx, err := sel.GetUsedProfileNameAndIndex(); n != "live" || idx != 1 || err != nil {
+		t.Errorf("Selection.GetUsedProfileNameAndIndex() = %v, %v, %v", n, idx, err)
+	}
+	if d, _ := file.GetProfileBy("default"); d.AccessKeyID == "ABCDEFGHIJK123456789" {
+		t.Errorf("Selection.SetDefaultTo() wrote the keys into the default section")
+	}
+	file.Content.DeleteSection("live")
+	if _, idx, err := sel.GetUsedProfileNameAndIndex(); idx != -1 || err == nil {
+		t.Errorf("Selection.GetUsedProfileNameAndIndex() = %v, %v, want error", idx, err)
+	}
+	if err := sel.UnSetDefault(); err != nil || s.Selected() != "" {
+		t.Errorf("Selection.UnSetDefault() error = %v, selected = %v", err, s.Selected())
+	}
+}
+
+func TestGetActiveProfile(t *testing.T) {
+	file, s, cleanup := testSettings(t)
+	defer cleanup()
+	manual, _ := ini.InsensitiveLoad([]byte("[default]\naws_access_key_id=X\n[dev]\naws_access_key_id=A"))
+	noDefault, _ := ini.InsensitiveLoad([]byte("[dev]\naws_access_key_id=A"))
+	s.Select("live")
+
+	tests := []struct {
+		name     string
+		switcher Switcher
+		wantName string
+		wantID   string
+		wantErr  bool
+	}{
+		{
+			name:     "0positiv - default section",
+			switcher: file,
+			wantName: "dev",
+			wantID:   "123456789ABCDEFGHIJK",
+		},
+		{
+			name:     "1positiv - manually changed default section",
+			switcher: &CredentialsFile{Content: manual},
+			wantName: "default",
+			wantID:   "X",
+		},
+		{
+			name:     "2positiv - selection",
+			switcher: &Selection{CredentialsFile: file, Settings: s},
+			wantName: "live",
+			wantID:   "ABCDEFGHIJK123456789",
+		},
+		{
+			name:     "3negativ - no default",
+			switcher: &CredentialsFile{Content: noDefault},
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n, p, err := GetActiveProfile(tt.switcher)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GetActiveProfile() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err == nil && (n != tt.wantName || p.AccessKeyID != tt.wantID) {
+				t.Errorf("GetActiveProfile() = %v, %v, want %v, %v", n, p.AccessKeyID, tt.wantName, tt.wantID)
+			}
+		})
+	}
+}
@@ -0,0 +1,259 @@
+//Copyright 2018 Peter Büschel
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package awsdefault
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultSTSEndpoint is the global endpoint of the AWS Security Token Service.
+	DefaultSTSEndpoint = "https://sts.amazonaws.com"
+	// DefaultSessionDuration is the lifetime of the session credentials requested with an MFA code.
+	DefaultSessionDuration = 12 * time.Hour
+
+	stsVersion    = "2011-06-15"
+	sessionSuffix = "-session"
+)
+
+var (
+	mfaCodePattern    = regexp.MustCompile(`^[0-9]{6}$`)
+	stsRegionPattern  = regexp.MustCompile(`^sts(?:-fips)?\.([a-z0-9-]+)\.amazonaws\.com`)
+	signedHeaderNames = []string{"content-type", "host", "x-amz-date"}
+)
+
+type (
+	// STS requests session credentials from the AWS Security Token Service.
+	STS struct {
+		Endpoint string        // URL of the service; DefaultSTSEndpoint if empty
+		Duration time.Duration // lifetime of the credentials; DefaultSessionDuration if 0
+		Client   *http.Client
+
+		now func() time.Time
+	}
+
+	// stsCredentials is the part of the responses of STS containing the credentials
+	stsCredentials struct {
+		AccessKeyID     string `xml:"AccessKeyId"`
+		SecretAccessKey string `xml:"SecretAccessKey"`
+		SessionToken    string `xml:"SessionToken"`
+		Expiration      string `xml:"Expiration"`
+	}
+
+	stsResponse struct {
+		Credentials stsCredentials `xml:"GetSessionTokenResult>Credentials"`
+	}
+
+	stsError struct {
+		Code    string `xml:"Error>Code"`
+		Message string `xml:"Error>Message"`
+	}
+)
+
+// NewSTS returns the client of the given STS endpoint; an empty endpoint means DefaultSTSEndpoint.
+func NewSTS(endpoint string) *STS {
+	return &STS{Endpoint: endpoint, Client: &http.Client{Timeout: 30 * time.Second}, now: time.Now}
+}
+
+// MFASerial returns the serial number or ARN of the MFA device required by the profile.
+func (p *Profile) MFASerial() string {
+	return p.keys["mfa_serial"]
+}
+
+// ValidateMFACode checks that the code consists of the six digits shown by an MFA device.
+func ValidateMFACode(code string) error {
+	if !mfaCodePattern.MatchString(code) {
+		return fmt.Errorf("the MFA code must consist of 6 digits")
+	}
+	return nil
+}
+
+// endpoint returns the URL of the service and the region used to sign the requests, which is
+// taken from regional endpoints like https://sts.eu-west-1.amazonaws.com
+func (s *STS) endpoint() (*url.URL, string, error) {
+	e := s.Endpoint
+	if len(e) == 0 {
+		e = DefaultSTSEndpoint
+	}
+	u, err := url.Parse(e)
+	if err != nil || len(u.Host) == 0 {
+		return nil, "", fmt.Errorf("%q is no valid STS endpoint", e)
+	}
+	if m := stsRegionPattern.FindStringSubmatch(u.Host); m != nil {
+		return u, m[1], nil
+	}
+	return u, "us-east-1", nil
+}
+
+// GetSessionToken exchanges the long-term keys of the profile and the current code of its MFA
+// device for session credentials. The returned profile contains the credentials, the region of
+// the given profile and the expiry.
+func (s *STS) GetSessionToken(p *Profile, code string) (*Profile, error) {
+	if len(p.MFASerial()) == 0 {
+		return nil, fmt.Errorf("the profile has no mfa_serial")
+	}
+	if err := ValidateMFACode(code); err != nil {
+		return nil, err
+	}
+	u, region, err := s.endpoint()
+	if err != nil {
+		return nil, err
+	}
+	duration := s.Duration
+	if duration == 0 {
+		duration = DefaultSessionDuration
+	}
+	body := []byte(url.Values{
+		"Action":          {"GetSessionToken"},
+		"Version":         {stsVersion},
+		"DurationSeconds": {strconv.Itoa(int(duration / time.Second))},
+		"SerialNumber":    {p.MFASerial()},
+		"TokenCode":       {code},
+	}.Encode())
+	req, err := http.NewRequest(http.MethodPost, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+	signV4(req, body, p, region, "sts", s.now())
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	content, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		var e stsError
+		if xml.Unmarshal(content, &e) != nil || len(e.Message) == 0 {
+			return nil, fmt.Errorf("STS responded with %s", resp.Status)
+		}
+		return nil, fmt.Errorf("%s: %s", e.Code, e.Message)
+	}
+	var r stsResponse
+	if err = xml.Unmarshal(content, &r); err != nil {
+		return nil, fmt.Errorf("unable to parse the response of STS: %s", err)
+	}
+	c := r.Credentials
+	expiry, err := time.Parse(time.RFC3339, c.Expiration)
+	if err != nil || len(c.AccessKeyID) == 0 || len(c.SecretAccessKey) == 0 {
+		return nil, fmt.Errorf("the response of STS contains no valid credentials")
+	}
+	return &Profile{
+		AccessKeyID:     c.AccessKeyID,
+		SecretAccessKey: Secret(c.SecretAccessKey),
+		SessionToken:    Secret(c.SessionToken),
+		Region:          p.Region,
+		keys:            map[string]string{"aws_expiration": expiry.UTC().Format(time.RFC3339)},
+	}, nil
+}
+
+// signV4 adds the headers of the AWS Signature Version 4 made with the keys of the profile to the
+// request. Only the query parameters, the content type, the host and the date are signed.
+func signV4(req *http.Request, body []byte, p *Profile, region, service string, now time.Time) {
+	date := now.UTC().Format("20060102T150405Z")
+	req.Header.Set("X-Amz-Date", date)
+	if len(p.SessionToken) > 0 {
+		req.Header.Set("X-Amz-Security-Token", p.SessionToken.Reveal())
+	}
+	var headers strings.Builder
+	for _, h := range signedHeaderNames {
+		v := req.Header.Get(h)
+		if h == "host" {
+			v = req.URL.Host
+		}
+		headers.WriteString(h + ":" + strings.TrimSpace(v) + "\n")
+	}
+	path := req.URL.EscapedPath()
+	if len(path) == 0 {
+		path = "/"
+	}
+	signed := strings.Join(signedHeaderNames, ";")
+	canonical := strings.Join([]string{
+		req.Method, path, req.URL.Query().Encode(), headers.String(), signed, hashHex(body),
+	}, "\n")
+	scope := strings.Join([]string{date[:8], region, service, "aws4_request"}, "/")
+	toSign := strings.Join([]string{"AWS4-HMAC-SHA256", date, scope, hashHex([]byte(canonical))}, "\n")
+
+	key := []byte("AWS4" + p.SecretAccessKey.Reveal())
+	for _, part := range []string{date[:8], region, service, "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		p.AccessKeyID, scope, signed, hex.EncodeToString(hmacSHA256(key, toSign))))
+}
+
+func hashHex(b []byte) string {
+	h := sha256.Sum256(b)
+	return hex.EncodeToString(h[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// SessionProfileName returns the name of the profile storing the session credentials of the
+// given profile with MFA.
+func SessionProfileName(name string) string {
+	return name + sessionSuffix
+}
+
+// ValidSession returns the name of the session profile of the given profile, if its credentials
+// are still valid for at least the given time.
+func (f *CredentialsFile) ValidSession(name string, within time.Duration) (string, bool) {
+	session := SessionProfileName(name)
+	p, err := f.GetProfileBy(session)
+	if err != nil {
+		return "", false
+	}
+	if t, ok := p.Expiry(); ok && time.Now().Add(within).Before(t) {
+		return session, true
+	}
+	return "", false
+}
+
+// StoreSession stores the session credentials obtained for the given profile, e.g. via
+// STS.GetSessionToken, in its session profile and returns the name of the session profile.
+func (f *CredentialsFile) StoreSession(name string, session *Profile) (string, error) {
+	n := SessionProfileName(name)
+	if err := validProfileName(n); err != nil {
+		return "", err
+	}
+	return n, f.update(func() error {
+		f.Content.DeleteSection(n)
+		sec := f.Content.Section(n)
+		_ = sec.ReflectFrom(session) // error cannot happen; session is always a pointer
+		if t, ok := session.Expiry(); ok {
+			sec.Key("aws_expiration").SetValue(t.UTC().Format(time.RFC3339))
+		}
+		return nil
+	})
+}
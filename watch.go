@@ -0,0 +1,116 @@
+//Copyright 2018 Peter Büschel
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package awsdefault
+
+import (
+	"sync"
+	"time"
+)
+
+// WatchDebounce is the time the credentials file must stay untouched before a change gets
+// delivered. Editors and tools often write a file in several steps.
+var WatchDebounce = 200 * time.Millisecond
+
+// FileEvent is delivered by Watch after the credentials file changed on disk. File contains the
+// reloaded content, which replaces the watched CredentialsFile.
+type FileEvent struct {
+	File *CredentialsFile
+	Err  error
+}
+
+// Watch observes the credentials file and delivers a FileEvent for every change, until done gets
+// closed. A file replaced via rename, as done by many editors, is handled as a change, too. The
+// awsdefault settings are observed as well, because they hold the profile chosen in the
+// credential_process mode; their changes also deliver the reloaded credentials file.
+func (f *CredentialsFile) Watch(done <-chan struct{}) (<-chan FileEvent, error) {
+	stop := make(chan struct{}) // ends the first watch, if the second one fails
+	changes, err := watchFile(f.Path, stop)
+	if err != nil {
+		return nil, err
+	}
+	settings, err := watchFile(settingsPath(), stop)
+	if err != nil {
+		close(stop)
+		return nil, err
+	}
+	go func() {
+		<-done
+		close(stop)
+	}()
+	changes = mergeChanges(changes, settings)
+	events := make(chan FileEvent)
+	go func() {
+		defer close(events)
+		for {
+			if _, ok := <-changes; !ok {
+				return
+			}
+			if !debounce(changes) {
+				return
+			}
+			file, err := loadCredentialsFile(f.Path)
+			select {
+			case events <- FileEvent{File: file, Err: err}:
+			case <-done:
+				return
+			}
+		}
+	}()
+	return events, nil
+}
+
+// mergeChanges forwards the signals of both channels; the result gets closed after both
+func mergeChanges(a, b <-chan struct{}) <-chan struct{} {
+	merged := make(chan struct{}, 1)
+	var wg sync.WaitGroup
+	forward := func(changes <-chan struct{}) {
+		defer wg.Done()
+		for range changes {
+			select {
+			case merged <- struct{}{}:
+			default: // a change is already pending
+			}
+		}
+	}
+	wg.Add(2)
+	go forward(a)
+	go forward(b)
+	go func() {
+		wg.Wait()
+		close(merged)
+	}()
+	return merged
+}
+
+// debounce waits until no further change arrives within WatchDebounce. It returns false, if the
+// changes channel got closed.
+func debounce(changes <-chan struct{}) bool {
+	timer := time.NewTimer(WatchDebounce)
+	defer timer.Stop()
+	for {
+		select {
+		case _, ok := <-changes:
+			if !ok {
+				return false
+			}
+			if !timer.Stop() {
+				<-timer.C
+			}
+			timer.Reset(WatchDebounce)
+		case <-timer.C:
+			return true
+		}
+	}
+}
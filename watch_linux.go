@@ -0,0 +1,82 @@
+//Copyright 2018 Peter Büschel
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+package awsdefault
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"unsafe"
+)
+
+// the directory is watched instead of the file itself, because a file replaced via rename
+// would silently drop the watch
+const inotifyMask = syscall.IN_CLOSE_WRITE | syscall.IN_MOVED_TO | syscall.IN_MOVED_FROM |
+	syscall.IN_CREATE | syscall.IN_DELETE
+
+// watchFile sends a signal for every inotify event touching the file at path, until done gets
+// closed.
+func watchFile(path string, done <-chan struct{}) (<-chan struct{}, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, os.NewSyscallError("inotify_init1", err)
+	}
+	if _, err = syscall.InotifyAddWatch(fd, filepath.Dir(path), inotifyMask); err != nil {
+		syscall.Close(fd)
+		return nil, os.NewSyscallError("inotify_add_watch", err)
+	}
+	// a non-blocking file descriptor is handled by the runtime poller; Close unblocks Read
+	in := os.NewFile(uintptr(fd), "inotify")
+	go func() {
+		<-done
+		in.Close()
+	}()
+
+	name := filepath.Base(path)
+	changes := make(chan struct{}, 1)
+	go func() {
+		defer close(changes)
+		buf := make([]byte, 64*(syscall.SizeofInotifyEvent+syscall.NAME_MAX+1))
+		for {
+			n, err := in.Read(buf)
+			if err != nil {
+				return
+			}
+			for offset := 0; offset+syscall.SizeofInotifyEvent <= n; {
+				e := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+				start := offset + syscall.SizeofInotifyEvent
+				offset = start + int(e.Len)
+				if e.Len == 0 || offset > n {
+					continue
+				}
+				raw := buf[start:offset]
+				for i, b := range raw { // the name is padded with NUL bytes
+					if b == 0 {
+						raw = raw[:i]
+						break
+					}
+				}
+				if string(raw) != name {
+					continue
+				}
+				select {
+				case changes <- struct{}{}:
+				default: // a change is already pending
+				}
+			}
+		}
+	}()
+	return changes, nil
+}
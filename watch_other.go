@@ -0,0 +1,62 @@
+//Copyright 2018 Peter Büschel
+//
+//   Licensed under the Apache License, Version 2.0 (the "License");
+//   you may not use this file except in compliance with the License.
+//   You may obtain a copy of the License at
+//
+//       http://www.apache.org/licenses/LICENSE-2.0
+//
+//   Unless required by applicable law or agreed to in writing, software
+//   distributed under the License is distributed on an "AS IS" BASIS,
+//   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//   See the License for the specific language governing permissions and
+//   limitations under the License.
+
+//go:build !linux
+// +build !linux
+
+package awsdefault
+
+import (
+	"os"
+	"time"
+)
+
+// watchInterval is the polling interval on systems without inotify
+var watchInterval = time.Second
+
+// watchFile polls the modification time and size of the file at path and sends a signal for
+// every change, until done gets closed.
+func watchFile(path string, done <-chan struct{}) (<-chan struct{}, error) {
+	stat := func() (time.Time, int64) {
+		info, err := os.Stat(path)
+		if err != nil {
+			return time.Time{}, -1
+		}
+		return info.ModTime(), info.Size()
+	}
+	changes := make(chan struct{}, 1)
+	go func() {
+		defer close(changes)
+		mod, size := stat()
+		ticker := time.NewTicker(watchInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				m, s := stat()
+				if m.Equal(mod) && s == size {
+					continue
+				}
+				mod, size = m, s
+				select {
+				case changes <- struct{}{}:
+				default:
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	return changes, nil
+}
@@ -0,0 +1,132 @@
+package awsdefault
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kylelemons/godebug/pretty"
+)
+
+func TestCredentialsFile_Watch(t *testing.T) {
+	WatchDebounce = 50 * time.Millisecond
+	dir, err := ioutil.TempDir("", "awsdefault")
+	if err != nil {
+		t.Fatalf("CredentialsFile.Watch() could not create temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "credentials")
+	os.Setenv("AWSDEFAULT_CONFIG_FILE", filepath.Join(dir, "awsdefault"))
+	defer os.Unsetenv("AWSDEFAULT_CONFIG_FILE")
+	if err = ioutil.WriteFile(path, testFileContent, 0600); err != nil {
+		t.Fatalf("CredentialsFile.Watch() could not write credentials file: %s", err)
+	}
+	f, err := loadCredentialsFile(path)
+	if err != nil {
+		t.Fatalf("CredentialsFile.Watch() could not load credentials file: %s", err)
+	}
+	done := make(chan struct{})
+	events, err := f.Watch(done)
+	if err != nil {
+		t.Fatalf("CredentialsFile.Watch() error = %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		change    func() error
+		wantNames []string
+	}{
+		{
+			name: "0positiv - file written in place",
+			change: func() error {
+				return ioutil.WriteFile(path, []byte("[a]\n[b]\n"), 0600)
+			},
+			wantNames: []string{"a", "b"},
+		},
+		{
+			name: "1positiv - file replaced via rename",
+			change: func() error {
+				tmp := filepath.Join(dir, "credentials.tmp")
+				if err := ioutil.WriteFile(tmp, []byte("[c]\n"), 0600); err != nil {
+					return err
+				}
+				return os.Rename(tmp, path)
+			},
+			wantNames: []string{"c"},
+		},
+		{
+			name: "2positiv - several writes are delivered as one change",
+			change: func() error {
+				for _, c := range []string{"[d]\n", "[d]\n[e]\n", "[d]\n[e]\n[f]\n"} {
+					if err := ioutil.WriteFile(path, []byte(c), 0600); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+			wantNames: []string{"d", "e", "f"},
+		},
+		{
+			name: "3positiv - changed settings deliver the credentials file",
+			change: func() error {
+				return ioutil.WriteFile(filepath.Join(dir, "awsdefault"), []byte("[awsdefault]\nselected=d\n"), 0600)
+			},
+			wantNames: []string{"d", "e", "f"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.change(); err != nil {
+				t.Fatalf("CredentialsFile.Watch() could not change the file: %s", err)
+			}
+			select {
+			case e := <-events:
+				if e.Err != nil {
+					t.Fatalf("CredentialsFile.Watch() event error = %v", e.Err)
+				}
+				if diff := pretty.Compare(tt.wantNames, e.File.GetProfilesNames()); diff != "" {
+					t.Errorf("CredentialsFile.Watch() diff: (-want +got)\n%s", diff)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatalf("CredentialsFile.Watch() no event received")
+			}
+		})
+	}
+
+	close(done)
+	select {
+	case _, ok := <-events:
+		if ok {
+			t.Errorf("CredentialsFile.Watch() unexpected event after done")
+		}
+	case <-time.After(5 * time.Second):
+		t.Errorf("CredentialsFile.Watch() channel not closed after done")
+	}
+}
+
+func TestCredentialsFile_Watch_failure(t *testing.T) {
+	dir, err := ioutil.TempDir("", "awsdefault")
+	if err != nil {
+		t.Fatalf("CredentialsFile.Watch() could not create temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	os.Setenv("AWSDEFAULT_CONFIG_FILE", filepath.Join(dir, "xxxxxxxx", "awsdefault"))
+	defer os.Unsetenv("AWSDEFAULT_CONFIG_FILE")
+	openFiles := func() int {
+		fds, _ := ioutil.ReadDir("/proc/self/fd")
+		return len(fds)
+	}
+	before := openFiles()
+	f := &CredentialsFile{Path: filepath.Join(dir, "credentials")}
+	if _, err = f.Watch(nil); err == nil {
+		t.Skip("CredentialsFile.Watch() can watch a missing directory on this system")
+	}
+	// the watch of the credentials file gets closed in the background
+	for start := time.Now(); openFiles() > before; time.Sleep(10 * time.Millisecond) {
+		if time.Since(start) > 5*time.Second {
+			t.Fatalf("CredentialsFile.Watch() left %d files open", openFiles()-before)
+		}
+	}
+}